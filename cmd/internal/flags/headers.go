@@ -0,0 +1,25 @@
+// Package flags has command line values shared by commands.
+package flags
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Headers collects repeated HTTP request headers in the format 'Name: value'.
+type Headers map[string]string
+
+func (f Headers) String() string {
+	return fmt.Sprintf("%v", map[string]string(f))
+}
+
+func (f Headers) Set(v string) error {
+	split := strings.SplitN(v, ":", 2)
+	if len(split) != 2 {
+		return fmt.Errorf("expected header in the format 'Name: value'")
+	}
+
+	f[strings.TrimSpace(split[0])] = strings.TrimSpace(split[1])
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package flags
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/input"
+	"github.com/dpb587/bosh-log-tracer/log/input/director"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug/parser"
+	"github.com/dpb587/bosh-log-tracer/observer"
+	"github.com/dpb587/bosh-log-tracer/pipeline"
+	"github.com/dpb587/bosh-log-tracer/redact"
+)
+
+// Input has the flags of commands which read task logs: which logs are read
+// (locally or from a director), how they are parsed and, optionally, what is
+// redacted from the result.
+type Input struct {
+	// Redact registers the redaction flags; Redactor is set once resolved.
+	// Commands which rewrite logs themselves (e.g. anonymizing) leave it unset.
+	Redact   bool
+	Redactor *redact.Redactor
+
+	redact   redact.Flags
+	parser   parser.Flags
+	director director.Flags
+	lenient  bool
+
+	parserOptions parser.Options
+}
+
+func (f *Input) Register(fs *flag.FlagSet) {
+	if f.Redact {
+		f.redact.Register(fs)
+	}
+
+	f.parser.Register(fs)
+	f.director.Register(fs)
+	fs.BoolVar(&f.lenient, "lenient", false, "report unexpected lines as warnings instead of failing")
+}
+
+// Resolve returns the inputs selected by args and the director flags (see
+// director.Flags.Resolve) once the other flags were checked.
+func (f *Input) Resolve(args []string, outputs ...director.OutputType) ([]input.Input, error) {
+	inputs, err := f.director.Resolve(args, outputs...)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.Redact {
+		f.Redactor, err = f.redact.Redactor()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	f.parserOptions, err = f.parser.Options()
+	if err != nil {
+		return nil, err
+	}
+
+	return inputs, nil
+}
+
+// Run parses an input into obs, printing any warnings when lenient.
+func (f *Input) Run(in input.Input, obs observer.Observer) error {
+	return f.run(obs, func(lineParser log.LineParser, obs observer.Observer) error {
+		return pipeline.RunConcurrently(in, lineParser, obs, f.parser.Workers())
+	})
+}
+
+// Follow is like Run for an input which is still being written; see
+// pipeline.Follow.
+func (f *Input) Follow(ctx context.Context, in input.Input, obs observer.Observer, flushInterval time.Duration) error {
+	return f.run(obs, func(lineParser log.LineParser, obs observer.Observer) error {
+		return pipeline.Follow(ctx, in, lineParser, obs, f.parser.Workers(), flushInterval)
+	})
+}
+
+func (f *Input) run(obs observer.Observer, run func(log.LineParser, observer.Observer) error) error {
+	diagnostics := &log.Diagnostics{}
+
+	var lineParser log.LineParser = parser.NewParser(f.parserOptions)
+
+	if f.lenient {
+		lineParser = parser.NewLenientParser(f.parserOptions, diagnostics)
+		obs = observer.NewLenientObserver(obs, diagnostics)
+	}
+
+	err := run(lineParser, obs)
+
+	for _, err := range diagnostics.Errors {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", err)
+	}
+
+	return err
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/dpb587/bosh-log-tracer/cmd/internal/flags"
+	"github.com/dpb587/bosh-log-tracer/log/input"
+	"github.com/dpb587/bosh-log-tracer/log/input/director"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug/anonymize"
+)
+
+var (
+	outputPath = flag.String("output", "-", "path to write the anonymized log to (default is STDOUT)")
+	salt       = flag.String("salt", "", "secret used to derive pseudonyms; reuse it for consistent pseudonyms across runs (default is random)")
+)
+
+var inputFlags = &flags.Input{}
+
+func main() {
+	inputFlags.Register(flag.CommandLine)
+	flag.Parse()
+
+	err := run()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	// only the debug log is rewritten, so task directories result in a log of
+	// the same shape
+	inputs, err := inputFlags.Resolve(flag.Args(), director.DebugOutput)
+	if err != nil {
+		return err
+	}
+
+	if *salt == "" {
+		*salt, err = randomSalt()
+		if err != nil {
+			return err
+		}
+	}
+
+	var w io.Writer = os.Stdout
+
+	if *outputPath != input.Stdin {
+		fh, err := os.Create(*outputPath)
+		if err != nil {
+			return fmt.Errorf("creating %s: %s", *outputPath, err)
+		}
+
+		defer fh.Close()
+
+		w = fh
+	}
+
+	buf := bufio.NewWriter(w)
+	anonymizer := anonymize.NewAnonymizer(*salt)
+
+	for _, in := range inputs {
+		err := inputFlags.Run(in, anonymize.NewObserver(anonymizer, buf))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = buf.Flush()
+	if err != nil {
+		return fmt.Errorf("writing %s: %s", *outputPath, err)
+	}
+
+	return nil
+}
+
+func randomSalt() (string, error) {
+	b := make([]byte, 16)
+
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", fmt.Errorf("generating salt: %s", err)
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -1,44 +1,40 @@
 package main
 
 import (
-	"bufio"
+	"flag"
+	"fmt"
 	"os"
 
-	"github.com/dpb587/bosh-log-tracer/log"
-	"github.com/dpb587/bosh-log-tracer/log/taskdebug/parser"
+	"github.com/dpb587/bosh-log-tracer/cmd/internal/flags"
+	"github.com/dpb587/bosh-log-tracer/log/input/director"
 	"github.com/dpb587/bosh-log-tracer/observer/debug"
 )
 
-func main() {
-	observer := debug.NewObserver()
-	observer.Begin()
-	defer observer.Commit()
-
-	var offset int64
+var inputFlags = &flags.Input{Redact: true}
 
-	scanner := bufio.NewScanner(os.Stdin)
-	buf := make([]byte, 1024*1024)
-	scanner.Buffer(buf, 1024*1024)
-	for scanner.Scan() {
-		offset += 1
+func main() {
+	inputFlags.Register(flag.CommandLine)
+	flag.Parse()
 
-		var l log.Line = log.RawLine{
-			RawLineOffset: offset,
-			RawLineData:   scanner.Text(),
-		}
+	err := run()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(1)
+	}
+}
 
-		l, err := parser.Parser.Parse(l)
-		if err != nil {
-			panic(err)
-		}
+func run() error {
+	inputs, err := inputFlags.Resolve(flag.Args(), director.TraceOutputs...)
+	if err != nil {
+		return err
+	}
 
-		err = observer.Handle(l)
+	for _, in := range inputs {
+		err := inputFlags.Run(in, debug.NewObserver(inputFlags.Redactor))
 		if err != nil {
-			panic(err)
+			return err
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		panic(err)
-	}
+	return nil
 }
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/dpb587/bosh-log-tracer/cmd/internal/flags"
+	"github.com/dpb587/bosh-log-tracer/log/input"
+	"github.com/dpb587/bosh-log-tracer/log/input/director"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug/htmlreport"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug/timeline"
+	"github.com/dpb587/bosh-log-tracer/observer/context"
+)
+
+var outputPath = flag.String("output", "-", "path to write the HTML report to (default is STDOUT)")
+
+var inputFlags = &flags.Input{Redact: true}
+
+func main() {
+	inputFlags.Register(flag.CommandLine)
+	flag.Parse()
+
+	err := run()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	inputs, err := inputFlags.Resolve(flag.Args(), director.TraceOutputs...)
+	if err != nil {
+		return err
+	}
+
+	report := &htmlreport.Report{}
+
+	for _, in := range inputs {
+		obs := htmlreport.NewObserver(&context.Context{}, htmlreport.ObserverOptions{
+			Timeline: timeline.ObserverOptions{
+				IncludeLogReferences: true,
+				Redactor:             inputFlags.Redactor,
+			},
+			Report: report,
+		})
+
+		err := inputFlags.Run(in, obs)
+		if err != nil {
+			return err
+		}
+	}
+
+	return write(*outputPath, report)
+}
+
+func write(path string, report *htmlreport.Report) error {
+	var w io.Writer = os.Stdout
+
+	if path != input.Stdin {
+		fh, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("creating %s: %s", path, err)
+		}
+
+		defer fh.Close()
+
+		w = fh
+	}
+
+	err := report.Write(w)
+	if err != nil {
+		return fmt.Errorf("writing %s: %s", path, err)
+	}
+
+	return nil
+}
@@ -1,49 +1,156 @@
 package main
 
 import (
-	"bufio"
+	gocontext "context"
+	"flag"
+	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/dpb587/bosh-log-tracer/cmd/internal/flags"
 	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/input"
+	"github.com/dpb587/bosh-log-tracer/log/input/director"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug"
 	"github.com/dpb587/bosh-log-tracer/log/taskdebug/jaeger"
-	"github.com/dpb587/bosh-log-tracer/log/taskdebug/parser"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug/timeline"
+	"github.com/dpb587/bosh-log-tracer/observer"
 	"github.com/dpb587/bosh-log-tracer/observer/context"
+	"github.com/dpb587/bosh-log-tracer/trace"
 )
 
+var headers = flags.Headers{}
+
+var (
+	stableIDs = flag.Bool("deterministic-ids", false, "derive trace and span IDs from the director, task and spans so repeated runs result in the same IDs")
+	jsonPath  = flag.String("json", "", "write traces to a Jaeger UI JSON file instead of sending them to jaeger")
+	agent     = flag.String("agent", "", "jaeger agent host:port to send spans to (default localhost:6831)")
+	collector = flag.String("collector", "", "jaeger collector URL to send spans to instead of an agent (e.g. http://localhost:14268/api/traces)")
+	uiURL     = flag.String("ui-url", jaeger.DefaultUIURL, "base URL of the Jaeger UI for printed trace links")
+	follow    = flag.Bool("follow", false, "keep reading a growing log of a running task until it finishes or is interrupted, sending spans as they finish")
+	flushEach = flag.Duration("flush-interval", 10*time.Second, "how often in-progress spans are sent as partial spans when following")
+)
+
+// traces are kept for writing to a file rather than sent to the agent
+var traces []*trace.Trace
+
+var inputFlags = &flags.Input{Redact: true}
+
 func main() {
+	inputFlags.Register(flag.CommandLine)
+	flag.Var(headers, "header", "additional collector request header in the format 'Name: value' (may be repeated)")
+	flag.Parse()
+
+	err := run()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	inputs, err := inputFlags.Resolve(flag.Args(), director.TraceOutputs...)
+	if err != nil {
+		return err
+	}
+
+	if *follow {
+		if len(inputs) != 1 {
+			return fmt.Errorf("following requires a single input")
+		} else if *jsonPath != "" {
+			return fmt.Errorf("following sends spans as they finish and cannot be combined with -json")
+		}
+	}
+
+	for _, in := range inputs {
+		err := traceInput(in)
+		if err != nil {
+			return err
+		}
+	}
+
+	if *jsonPath != "" {
+		return writeJSON(*jsonPath, traces)
+	}
+
+	return nil
+}
+
+func traceInput(in input.Input) error {
 	ctx := &context.Context{}
 
-	observer := jaeger.NewObserver(ctx, jaeger.ObserverOptions{
+	timelineOptions := timeline.ObserverOptions{
 		IncludeLogReferences: true,
+		Redactor:             inputFlags.Redactor,
+		DeterministicIDs:     *stableIDs,
+	}
+
+	if *jsonPath != "" {
+		obs := timeline.NewObserver(ctx, timelineOptions)
+
+		traces = append(traces, obs.Trace())
+
+		return inputFlags.Run(in, obs)
+	}
+
+	obs := jaeger.NewObserver(ctx, jaeger.ObserverOptions{
+		Timeline: timelineOptions,
+		Endpoint: jaeger.Endpoint{
+			AgentHostPort:     *agent,
+			CollectorEndpoint: *collector,
+			Headers:           headers,
+		},
+		UIURL: *uiURL,
 	})
-	observer.Begin()
-	defer observer.Commit()
 
-	var offset int64
+	if *follow {
+		return followInput(in, obs)
+	}
 
-	scanner := bufio.NewScanner(os.Stdin)
-	buf := make([]byte, 1024*1024)
-	scanner.Buffer(buf, 1024*1024)
-	for scanner.Scan() {
-		offset += 1
+	return inputFlags.Run(in, obs)
+}
 
-		var l log.Line = log.RawLine{
-			RawLineOffset: offset,
-			RawLineData:   scanner.Text(),
-		}
+// followInput traces a task while it runs; following stops once the task
+// finished or on an interrupt, after which the remaining lines are traced.
+func followInput(in input.Input, obs observer.Observer) error {
+	ctx, stop := signal.NotifyContext(gocontext.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-		l, err := parser.Parser.Parse(l)
-		if err != nil {
-			panic(err)
-		}
+	return inputFlags.Follow(ctx, in, taskStateObserver{Observer: obs, stop: stop}, *flushEach)
+}
 
-		err = observer.Handle(l)
-		if err != nil {
-			panic(err)
-		}
+// taskStateObserver stops following once the final state of the task was
+// logged.
+type taskStateObserver struct {
+	observer.Observer
+
+	stop func()
+}
+
+func (o taskStateObserver) Handle(msg log.Line) error {
+	if _, ok := msg.(taskdebug.TaskStateMessage); ok {
+		o.stop()
 	}
 
-	if err := scanner.Err(); err != nil {
-		panic(err)
+	return o.Observer.Handle(msg)
+}
+
+func writeJSON(path string, traces []*trace.Trace) error {
+	fh, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating %s: %s", path, err)
 	}
+
+	defer fh.Close()
+
+	err = jaeger.WriteUIJSON(fh, traces...)
+	if err != nil {
+		return fmt.Errorf("writing %s: %s", path, err)
+	}
+
+	fmt.Printf("wrote %d traces to %s\n", len(traces), path)
+
+	return nil
 }
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/dpb587/bosh-log-tracer/cmd/internal/flags"
+	"github.com/dpb587/bosh-log-tracer/log/input/director"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug/otlp"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug/timeline"
+	"github.com/dpb587/bosh-log-tracer/observer/context"
+)
+
+var headers = flags.Headers{}
+
+var (
+	stableIDs    = flag.Bool("deterministic-ids", false, "derive trace and span IDs from the director, task and spans so repeated runs result in the same IDs")
+	endpoint     = flag.String("endpoint", otlp.DefaultEndpoint, "OTLP/HTTP traces endpoint")
+	encoding     = flag.String("encoding", otlp.EncodingProtobuf, "OTLP/HTTP encoding (protobuf, json)")
+	directorName = flag.String("director-name", "", "director name to include as a resource attribute")
+	deployment   = flag.String("deployment", "", "deployment name to include as a resource attribute (default is the task's deployment)")
+)
+
+var inputFlags = &flags.Input{Redact: true}
+
+func main() {
+	inputFlags.Register(flag.CommandLine)
+	flag.Var(headers, "header", "additional request header in the format 'Name: value' (may be repeated)")
+	flag.Parse()
+
+	err := run()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	inputs, err := inputFlags.Resolve(flag.Args(), director.TraceOutputs...)
+	if err != nil {
+		return err
+	}
+
+	for _, in := range inputs {
+		obs := otlp.NewObserver(&context.Context{}, otlp.ObserverOptions{
+			Timeline: timeline.ObserverOptions{
+				IncludeLogReferences: true,
+				Redactor:             inputFlags.Redactor,
+				DeterministicIDs:     *stableIDs,
+			},
+			Endpoint:     *endpoint,
+			Encoding:     *encoding,
+			Headers:      headers,
+			DirectorName: *directorName,
+			Deployment:   *deployment,
+		})
+
+		err := inputFlags.Run(in, obs)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
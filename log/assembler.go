@@ -0,0 +1,72 @@
+package log
+
+import "strings"
+
+// MaxEntrySize limits how much data is folded into a single entry so input
+// which never matches an entry start is not buffered in its entirety.
+const MaxEntrySize = 1024 * 1024
+
+// Assembler folds continuation lines into the entry they follow before
+// handing entries to handler. Entries keep the offset of their first line and
+// record the offset of their last.
+type Assembler struct {
+	matcher EntryMatcher
+	handler func(Line) error
+
+	pending     *RawLine
+	pendingData []string
+	pendingSize int
+}
+
+func NewAssembler(matcher EntryMatcher, handler func(Line) error) *Assembler {
+	return &Assembler{
+		matcher: matcher,
+		handler: handler,
+	}
+}
+
+// Add buffers a physical line, handling the previous entry once the line is
+// known to start a new one. Entries never continue across sources, so a line
+// without a header at the start of a source begins an entry of its own.
+func (a *Assembler) Add(l Line) error {
+	data := l.LineData()
+
+	if a.pending != nil && a.pending.RawLineSource == l.LineSource() && !a.matcher.IsEntryStart(data) && a.pendingSize+1+len(data) <= MaxEntrySize {
+		a.pending.RawLineEndOffset = l.LineOffset()
+		a.pendingData = append(a.pendingData, data)
+		a.pendingSize += 1 + len(data)
+
+		return nil
+	}
+
+	err := a.Flush()
+	if err != nil {
+		return err
+	}
+
+	a.pending = &RawLine{
+		RawLineSource: l.LineSource(),
+		RawLineOffset: l.LineOffset(),
+	}
+	a.pendingData = []string{data}
+	a.pendingSize = len(data)
+
+	return nil
+}
+
+// Flush handles the buffered entry, if any; it must be called once the input
+// has been exhausted.
+func (a *Assembler) Flush() error {
+	if a.pending == nil {
+		return nil
+	}
+
+	entry := *a.pending
+	entry.RawLineData = strings.Join(a.pendingData, "\n")
+
+	a.pending = nil
+	a.pendingData = nil
+	a.pendingSize = 0
+
+	return a.handler(entry)
+}
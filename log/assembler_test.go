@@ -0,0 +1,127 @@
+package log
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type prefixMatcher string
+
+func (m prefixMatcher) IsEntryStart(data string) bool {
+	return strings.HasPrefix(data, string(m))
+}
+
+func TestAssembler(t *testing.T) {
+	var actual []Line
+
+	assembler := NewAssembler(prefixMatcher("> "), func(l Line) error {
+		actual = append(actual, l)
+
+		return nil
+	})
+
+	for idx, data := range []string{"orphan", "> one", "> two", "  continued", "  again", "> three"} {
+		err := assembler.Add(RawLine{RawLineSource: "test.log", RawLineOffset: int64(idx + 1), RawLineData: data})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	if len(actual) != 3 {
+		t.Fatalf("expected the last entry to be buffered until flushed, but got %d entries", len(actual))
+	}
+
+	err := assembler.Flush()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := []Line{
+		RawLine{RawLineSource: "test.log", RawLineOffset: 1, RawLineData: "orphan"},
+		RawLine{RawLineSource: "test.log", RawLineOffset: 2, RawLineData: "> one"},
+		RawLine{RawLineSource: "test.log", RawLineOffset: 3, RawLineEndOffset: 5, RawLineData: "> two\n  continued\n  again"},
+		RawLine{RawLineSource: "test.log", RawLineOffset: 6, RawLineData: "> three"},
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected:\n%#+v\nactual:\n%#+v", expected, actual)
+	}
+
+	if end := actual[2].LineEndOffset(); end != 5 {
+		t.Errorf("expected end offset 5, but got %d", end)
+	}
+
+	if end := actual[0].LineEndOffset(); end != 1 {
+		t.Errorf("expected end offset 1, but got %d", end)
+	}
+}
+
+func TestAssemblerMaxEntrySize(t *testing.T) {
+	var actual []Line
+
+	assembler := NewAssembler(prefixMatcher("> "), func(l Line) error {
+		actual = append(actual, l)
+
+		return nil
+	})
+
+	chunk := strings.Repeat("x", MaxEntrySize/2)
+
+	for idx, data := range []string{"> big", chunk, chunk} {
+		err := assembler.Add(RawLine{RawLineOffset: int64(idx + 1), RawLineData: data})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	err := assembler.Flush()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(actual) != 2 {
+		t.Fatalf("expected the entry to be split at the size limit, but got %d entries", len(actual))
+	} else if actual[1].LineOffset() != 3 {
+		t.Errorf("expected the second entry to start at line 3, but got %d", actual[1].LineOffset())
+	}
+}
+
+func TestAssemblerSources(t *testing.T) {
+	var actual []Line
+
+	assembler := NewAssembler(prefixMatcher("> "), func(l Line) error {
+		actual = append(actual, l)
+
+		return nil
+	})
+
+	for _, l := range []RawLine{
+		{RawLineSource: "event", RawLineOffset: 1, RawLineData: "> one"},
+		{RawLineSource: "cpi", RawLineOffset: 1, RawLineData: "// headerless"},
+		{RawLineSource: "cpi", RawLineOffset: 2, RawLineData: "  continued"},
+		{RawLineSource: "debug", RawLineOffset: 1, RawLineData: "  orphan"},
+		{RawLineSource: "debug", RawLineOffset: 2, RawLineData: "> two"},
+	} {
+		err := assembler.Add(l)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	err := assembler.Flush()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := []Line{
+		RawLine{RawLineSource: "event", RawLineOffset: 1, RawLineData: "> one"},
+		RawLine{RawLineSource: "cpi", RawLineOffset: 1, RawLineEndOffset: 2, RawLineData: "// headerless\n  continued"},
+		RawLine{RawLineSource: "debug", RawLineOffset: 1, RawLineData: "  orphan"},
+		RawLine{RawLineSource: "debug", RawLineOffset: 2, RawLineData: "> two"},
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected:\n%#+v\nactual:\n%#+v", expected, actual)
+	}
+}
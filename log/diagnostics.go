@@ -0,0 +1,40 @@
+package log
+
+import "sync"
+
+// Diagnostics collects errors which were tolerated in lenient mode. Errors may
+// be added concurrently.
+type Diagnostics struct {
+	Errors []error
+
+	mu sync.Mutex
+}
+
+// Add records err; errors of lines are kept in the order of their lines even
+// when lines are parsed concurrently.
+func (d *Diagnostics) Add(err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	idx := len(d.Errors)
+
+	if lineErr, ok := err.(LineError); ok {
+		for ; idx > 0; idx-- {
+			prev, ok := d.Errors[idx-1].(LineError)
+			if !ok || prev.Source != lineErr.Source || prev.LineOffset <= lineErr.LineOffset {
+				break
+			}
+		}
+	}
+
+	d.Errors = append(d.Errors, nil)
+	copy(d.Errors[idx+1:], d.Errors[idx:])
+	d.Errors[idx] = err
+}
+
+func (d *Diagnostics) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return len(d.Errors)
+}
@@ -0,0 +1,45 @@
+package log
+
+import "fmt"
+
+// LineError is returned by parsers and observers when they are unable to handle
+// a specific line.
+type LineError struct {
+	Source     string
+	LineOffset int64
+	Err        error
+}
+
+var _ error = LineError{}
+
+// NewLineError wraps err with the source and offset of line. Errors which
+// already reference a line are returned unchanged.
+func NewLineError(line Line, err error) error {
+	if err == nil {
+		return nil
+	} else if _, ok := err.(LineError); ok {
+		return err
+	}
+
+	return LineError{
+		Source:     line.LineSource(),
+		LineOffset: line.LineOffset(),
+		Err:        err,
+	}
+}
+
+func (e LineError) Error() string {
+	if e.Source == "" {
+		return fmt.Sprintf("line %d: %s", e.LineOffset, e.Err)
+	}
+
+	return fmt.Sprintf("%s:%d: %s", e.Source, e.LineOffset, e.Err)
+}
+
+func (e LineError) Cause() error {
+	return e.Err
+}
+
+func (e LineError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,225 @@
+package director
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ClientOptions configure how a director is reached and authenticated with.
+type ClientOptions struct {
+	// URL of the director (e.g. https://192.168.50.6:25555).
+	URL string
+
+	// CACert is the PEM-encoded CA certificate the director (and UAA) are
+	// verified with; the system roots are used by default.
+	CACert string
+
+	// Client and ClientSecret are the UAA client credentials, or the username
+	// and password when the director uses basic authentication.
+	Client       string
+	ClientSecret string
+}
+
+// Client talks to the director API, authenticating according to the director's
+// user_authentication.
+type Client struct {
+	url          string
+	httpClient   *http.Client
+	client       string
+	clientSecret string
+
+	mu          sync.Mutex
+	authType    string
+	uaaURL      string
+	token       string
+	tokenExpiry time.Time
+}
+
+func NewClient(o ClientOptions) (*Client, error) {
+	if o.URL == "" {
+		return nil, fmt.Errorf("director URL is required")
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if o.CACert != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(o.CACert)) {
+			return nil, fmt.Errorf("parsing director CA certificate: no certificates found")
+		}
+
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	return &Client{
+		url:          strings.TrimSuffix(o.URL, "/"),
+		httpClient:   &http.Client{Transport: transport},
+		client:       o.Client,
+		clientSecret: o.ClientSecret,
+	}, nil
+}
+
+type infoResponse struct {
+	UserAuthentication struct {
+		Type    string `json:"type"`
+		Options struct {
+			URL string `json:"url"`
+		} `json:"options"`
+	} `json:"user_authentication"`
+}
+
+// authorization returns the Authorization header for director requests,
+// requesting a new UAA token once the previous one is about to expire.
+func (c *Client) authorization() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.authType == "" {
+		body, err := c.request("/info", "")
+		if err != nil {
+			return "", err
+		}
+
+		var info infoResponse
+
+		err = decodeJSON("/info", body, &info)
+		if err != nil {
+			return "", err
+		}
+
+		c.authType = info.UserAuthentication.Type
+		c.uaaURL = strings.TrimSuffix(info.UserAuthentication.Options.URL, "/")
+	}
+
+	switch c.authType {
+	case "basic":
+		req := http.Request{Header: http.Header{}}
+		req.SetBasicAuth(c.client, c.clientSecret)
+
+		return req.Header.Get("Authorization"), nil
+	case "uaa":
+		if c.token == "" || time.Now().After(c.tokenExpiry) {
+			err := c.requestToken()
+			if err != nil {
+				return "", err
+			}
+		}
+
+		return c.token, nil
+	}
+
+	return "", fmt.Errorf("unsupported director authentication: %s", c.authType)
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+func (c *Client) requestToken() error {
+	form := url.Values{"grant_type": {"client_credentials"}}
+
+	req, err := http.NewRequest(http.MethodPost, c.uaaURL+"/oauth/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("requesting UAA token: %s", err)
+	}
+
+	req.SetBasicAuth(url.QueryEscape(c.client), url.QueryEscape(c.clientSecret))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	res, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("requesting UAA token: %s", err)
+	}
+
+	defer res.Body.Close()
+
+	var token tokenResponse
+
+	err = json.NewDecoder(res.Body).Decode(&token)
+	if err != nil {
+		return fmt.Errorf("decoding UAA token: %s", err)
+	} else if token.AccessToken == "" {
+		return fmt.Errorf("decoding UAA token: missing access_token")
+	}
+
+	if token.TokenType == "" {
+		token.TokenType = "bearer"
+	}
+
+	c.token = fmt.Sprintf("%s %s", token.TokenType, token.AccessToken)
+
+	// renew early rather than racing the expiry during a download
+	c.tokenExpiry = time.Now().Add(time.Duration(token.ExpiresIn)*time.Second - time.Minute)
+
+	return nil
+}
+
+// get requests a director path, returning the body of a successful response.
+func (c *Client) get(path string) (io.ReadCloser, error) {
+	authorization, err := c.authorization()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.request(path, authorization)
+}
+
+func (c *Client) request(path, authorization string) (io.ReadCloser, error) {
+	req, err := http.NewRequest(http.MethodGet, c.url+path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("requesting %s: %s", path, err)
+	}
+
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	res, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("requesting %s: %s", path, err)
+	}
+
+	return res.Body, nil
+}
+
+func decodeJSON(path string, body io.ReadCloser, v interface{}) error {
+	defer body.Close()
+
+	err := json.NewDecoder(body).Decode(v)
+	if err != nil {
+		return fmt.Errorf("decoding %s: %s", path, err)
+	}
+
+	return nil
+}
+
+// do sends a request, treating any status other than 200 (or 204, for a task
+// output which does not exist) as an error.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusNoContent {
+		defer res.Body.Close()
+
+		body, _ := ioutil.ReadAll(io.LimitReader(res.Body, 512))
+
+		return nil, fmt.Errorf("unexpected status %s: %s", res.Status, strings.TrimSpace(string(body)))
+	}
+
+	return res, nil
+}
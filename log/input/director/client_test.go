@@ -0,0 +1,332 @@
+package director
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/input"
+)
+
+// fakeDirector serves the parts of the director (and UAA) API used by the
+// client.
+type fakeDirector struct {
+	*httptest.Server
+
+	authType string
+	tasks    []Task
+	outputs  map[string]string
+
+	tokens      int
+	taskQueries []string
+}
+
+func newFakeDirector(t *testing.T, authType string) *fakeDirector {
+	d := &fakeDirector{
+		authType: authType,
+		tasks: []Task{
+			{ID: 3, State: "error", Description: "create deployment", Timestamp: 1560916000, Deployment: "web"},
+			{ID: 2, State: "done", Description: "delete deployment", Timestamp: 1560915000, Deployment: "web"},
+			{ID: 1, State: "done", Description: "create deployment", Timestamp: 1560900000, Deployment: "web"},
+		},
+		outputs: map[string]string{
+			"/tasks/3/output?type=debug":  "I, [2019-06-19T01:44:47.701020 #26587] [0x2ab4]  INFO -- TaskHelper: Director Version: 269.0.1\nTask 3 error\n",
+			"/tasks/3/output?type=event":  `{"time":1560915999,"stage":"Preparing deployment","state":"started"}` + "\n",
+			"/tasks/3/output?type=result": "Failed to compile packages\n",
+		},
+	}
+
+	d.Server = httptest.NewTLSServer(http.HandlerFunc(d.serve))
+	t.Cleanup(d.Close)
+
+	return d
+}
+
+func (d *fakeDirector) caCert() string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: d.Certificate().Raw}))
+}
+
+func (d *fakeDirector) serve(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/info":
+		fmt.Fprintf(w, `{"name":"fake","user_authentication":{"type":%q,"options":{"url":%q}}}`, d.authType, d.URL+"/uaa")
+
+		return
+	case "/uaa/oauth/token":
+		client, secret, _ := r.BasicAuth()
+		if r.Method != http.MethodPost || r.FormValue("grant_type") != "client_credentials" || client != "admin" || secret != "s3cr3t" {
+			http.Error(w, "bad credentials", http.StatusUnauthorized)
+
+			return
+		}
+
+		d.tokens++
+
+		fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"bearer","expires_in":3600}`, d.tokens)
+
+		return
+	}
+
+	if !d.authorized(r) {
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+
+		return
+	} else if r.URL.Path == "/tasks" {
+		d.taskQueries = append(d.taskQueries, r.URL.RawQuery)
+
+		tasks := d.tasks
+
+		if limit, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && limit < len(tasks) {
+			tasks = tasks[0:limit]
+		}
+
+		json.NewEncoder(w).Encode(tasks)
+
+		return
+	}
+
+	output, found := d.outputs[r.URL.RequestURI()]
+	if !found {
+		if strings.HasPrefix(r.URL.Path, "/tasks/3/") {
+			// outputs which were never written
+			w.WriteHeader(http.StatusNoContent)
+		} else {
+			http.NotFound(w, r)
+		}
+
+		return
+	}
+
+	fmt.Fprint(w, output)
+}
+
+func (d *fakeDirector) authorized(r *http.Request) bool {
+	if d.authType == "basic" {
+		username, password, ok := r.BasicAuth()
+
+		return ok && username == "admin" && password == "s3cr3t"
+	}
+
+	return r.Header.Get("Authorization") == fmt.Sprintf("bearer token-%d", d.tokens)
+}
+
+func (d *fakeDirector) client(t *testing.T, secret string) *Client {
+	c, err := NewClient(ClientOptions{
+		URL:          d.URL,
+		CACert:       d.caCert(),
+		Client:       "admin",
+		ClientSecret: secret,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	return c
+}
+
+func taskIDs(tasks []Task) []int {
+	var res []int
+
+	for _, task := range tasks {
+		res = append(res, task.ID)
+	}
+
+	return res
+}
+
+func TestClientTasks(t *testing.T) {
+	for _, authType := range []string{"basic", "uaa"} {
+		t.Run(authType, func(t *testing.T) {
+			d := newFakeDirector(t, authType)
+			c := d.client(t, "s3cr3t")
+
+			tasks, err := c.Tasks(TaskFilter{})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			} else if ids := taskIDs(tasks); !reflect.DeepEqual(ids, []int{3, 2, 1}) {
+				t.Fatalf("expected all tasks, but got %v", ids)
+			}
+
+			_, err = c.Tasks(TaskFilter{})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			} else if authType == "uaa" && d.tokens != 1 {
+				t.Fatalf("expected the token to be reused, but got %d tokens", d.tokens)
+			}
+		})
+	}
+}
+
+func TestClientTasksFilter(t *testing.T) {
+	d := newFakeDirector(t, "basic")
+	c := d.client(t, "s3cr3t")
+
+	tasks, err := c.Tasks(TaskFilter{
+		Deployment:   "web",
+		States:       []string{"done", "error"},
+		Descriptions: []string{"Create Deployment"},
+		Since:        time.Unix(1560910000, 0),
+		Limit:        10,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if ids := taskIDs(tasks); !reflect.DeepEqual(ids, []int{3}) {
+		t.Fatalf("expected filtered tasks, but got %v", ids)
+	}
+
+	if expected := []string{"deployment=web&limit=10&state=done%2Cerror&verbose=2"}; !reflect.DeepEqual(d.taskQueries, expected) {
+		t.Fatalf("expected queries %v, but got %v", expected, d.taskQueries)
+	}
+}
+
+func TestClientTasksLimitAfterFilter(t *testing.T) {
+	tests := []struct {
+		filter          TaskFilter
+		expected        []int
+		expectedQueries []string
+	}{
+		{
+			filter:          TaskFilter{Limit: 2},
+			expected:        []int{3, 2},
+			expectedQueries: []string{"limit=2&verbose=2"},
+		},
+		{
+			filter:          TaskFilter{Descriptions: []string{"create deployment"}, Limit: 2},
+			expected:        []int{3, 1},
+			expectedQueries: []string{"limit=2&verbose=2", "limit=4&verbose=2"},
+		},
+		{
+			filter:          TaskFilter{Descriptions: []string{"delete deployment"}, Limit: 1},
+			expected:        []int{2},
+			expectedQueries: []string{"limit=1&verbose=2", "limit=2&verbose=2"},
+		},
+		{
+			filter:          TaskFilter{Descriptions: []string{"create deployment"}, Since: time.Unix(1560915500, 0), Limit: 1},
+			expected:        []int{3},
+			expectedQueries: []string{"limit=1&verbose=2"},
+		},
+		{
+			filter:          TaskFilter{Descriptions: []string{"delete deployment"}, Since: time.Unix(1560915500, 0), Limit: 2},
+			expected:        nil,
+			expectedQueries: []string{"limit=2&verbose=2"},
+		},
+		{
+			filter:          TaskFilter{Types: []string{"Delete"}, Limit: 1},
+			expected:        []int{2},
+			expectedQueries: []string{"limit=1&verbose=2", "limit=2&verbose=2"},
+		},
+		{
+			filter:          TaskFilter{Types: []string{"create deployment", "delete"}, Descriptions: []string{"create deployment"}, Limit: 3},
+			expected:        []int{3, 1},
+			expectedQueries: []string{"limit=3&verbose=2", "limit=6&verbose=2"},
+		},
+		{
+			filter:          TaskFilter{Types: []string{"create deployment web"}, Limit: 1},
+			expected:        nil,
+			expectedQueries: []string{"limit=1&verbose=2", "limit=2&verbose=2", "limit=4&verbose=2"},
+		},
+		{
+			filter:          TaskFilter{Descriptions: []string{"recreate"}, Limit: 1},
+			expected:        nil,
+			expectedQueries: []string{"limit=1&verbose=2", "limit=2&verbose=2", "limit=4&verbose=2"},
+		},
+	}
+
+	for _, tt := range tests {
+		d := newFakeDirector(t, "basic")
+
+		tasks, err := d.client(t, "s3cr3t").Tasks(tt.filter)
+		if err != nil {
+			t.Fatalf("%+v: unexpected error: %s", tt.filter, err)
+		}
+
+		if ids := taskIDs(tasks); !reflect.DeepEqual(ids, tt.expected) {
+			t.Errorf("%+v: expected tasks %v, but got %v", tt.filter, tt.expected, ids)
+		} else if !reflect.DeepEqual(d.taskQueries, tt.expectedQueries) {
+			t.Errorf("%+v: expected queries %v, but got %v", tt.filter, tt.expectedQueries, d.taskQueries)
+		}
+	}
+}
+
+func TestClientUnauthorized(t *testing.T) {
+	for _, authType := range []string{"basic", "uaa"} {
+		t.Run(authType, func(t *testing.T) {
+			d := newFakeDirector(t, authType)
+
+			_, err := d.client(t, "wrong").Tasks(TaskFilter{})
+			if err == nil || !strings.Contains(err.Error(), "401") {
+				t.Fatalf("expected an unauthorized error, but got %v", err)
+			}
+		})
+	}
+}
+
+func TestClientUntrusted(t *testing.T) {
+	d := newFakeDirector(t, "basic")
+
+	c, err := NewClient(ClientOptions{URL: d.URL})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	_, err = c.Tasks(TaskFilter{})
+	if err == nil {
+		t.Fatal("expected a certificate error")
+	}
+}
+
+func TestClientInput(t *testing.T) {
+	d := newFakeDirector(t, "uaa")
+	c := d.client(t, "s3cr3t")
+
+	for _, tt := range []struct {
+		output   OutputType
+		expected []string
+	}{
+		{DebugOutput, []string{"task/3/debug:1", "task/3/debug:2"}},
+		{EventOutput, []string{"task/3/event:1"}},
+		{CPIOutput, nil},
+		{ResultOutput, []string{"task/3/result:1"}},
+	} {
+		var actual []string
+
+		err := c.Input(3, tt.output).Scan(func(l log.Line) error {
+			actual = append(actual, fmt.Sprintf("%s:%d", l.LineSource(), l.LineOffset()))
+
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		} else if !reflect.DeepEqual(actual, tt.expected) {
+			t.Fatalf("expected %v, but got %v", tt.expected, actual)
+		}
+	}
+
+	var actual []string
+
+	err := input.Concat(c.Input(3, EventOutput), c.Input(3, DebugOutput)).Scan(func(l log.Line) error {
+		actual = append(actual, fmt.Sprintf("%s:%d", l.LineSource(), l.LineOffset()))
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if expected := []string{"task/3/event:1", "task/3/debug:1", "task/3/debug:2"}; !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("expected %v, but got %v", expected, actual)
+	}
+
+	err = c.Input(4, DebugOutput).Scan(func(log.Line) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("expected a not found error, but got %v", err)
+	}
+}
@@ -0,0 +1,180 @@
+package director
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dpb587/bosh-log-tracer/log/input"
+)
+
+// Flags are the director options shared by commands.
+type Flags struct {
+	url          string
+	caCert       string
+	client       string
+	clientSecret string
+
+	deployment   string
+	states       string
+	descriptions string
+	types        string
+	since        string
+	limit        int
+}
+
+func (f *Flags) Register(fs *flag.FlagSet) {
+	fs.StringVar(&f.url, "director", "", "director URL to fetch task logs from; arguments are then task IDs, or tasks are listed when there are none")
+	fs.StringVar(&f.caCert, "director-ca-cert", "", "path to, or contents of, the director CA certificate (default $BOSH_CA_CERT)")
+	fs.StringVar(&f.client, "director-client", "", "UAA client, or username for basic authentication (default $BOSH_CLIENT)")
+	fs.StringVar(&f.clientSecret, "director-client-secret", "", "UAA client secret, or password for basic authentication (default $BOSH_CLIENT_SECRET)")
+	fs.StringVar(&f.deployment, "task-deployment", "", "only list tasks of a deployment")
+	fs.StringVar(&f.states, "task-state", "", "only list tasks in one of the comma-separated states (e.g. done,error)")
+	fs.StringVar(&f.descriptions, "task-description", "", "only list tasks with one of the comma-separated descriptions (e.g. create deployment)")
+	fs.StringVar(&f.types, "task-type", "", "only list tasks of one of the comma-separated types, which their description starts with (e.g. run errand)")
+	fs.StringVar(&f.since, "task-since", "", "only list tasks which changed since a duration ago (e.g. 24h) or a time (e.g. 2019-06-19T00:00:00Z)")
+	fs.IntVar(&f.limit, "task-limit", 30, "most tasks to list after filtering")
+}
+
+// taskDirectoryLogs are the files of the outputs which are read before the
+// debug log of a local task directory.
+var taskDirectoryLogs = map[OutputType][]string{
+	EventOutput: input.TaskDirectoryEvents,
+	CPIOutput:   input.TaskDirectoryCPILogs,
+}
+
+// Resolve returns the outputs of the director tasks selected by the flags, with
+// the outputs of each task combined into a single input. If no director was
+// configured, args are resolved as local inputs instead, reading the same
+// outputs of task directories.
+func (f *Flags) Resolve(args []string, outputs ...OutputType) ([]input.Input, error) {
+	if f.url == "" {
+		var parts [][]string
+
+		for _, output := range outputs {
+			if names, found := taskDirectoryLogs[output]; found {
+				parts = append(parts, names)
+			}
+		}
+
+		return input.ResolveTaskLogs(parts, args...)
+	}
+
+	client, err := f.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []int
+
+	if len(args) > 0 {
+		for _, arg := range args {
+			id, err := strconv.Atoi(arg)
+			if err != nil {
+				return nil, fmt.Errorf("parsing task ID %s: %s", arg, err)
+			}
+
+			ids = append(ids, id)
+		}
+	} else {
+		filter, err := f.Filter()
+		if err != nil {
+			return nil, err
+		}
+
+		tasks, err := client.Tasks(filter)
+		if err != nil {
+			return nil, err
+		}
+
+		// oldest first, like local inputs in the order they are given
+		for i := len(tasks) - 1; i >= 0; i-- {
+			ids = append(ids, tasks[i].ID)
+		}
+	}
+
+	var res []input.Input
+
+	for _, id := range ids {
+		var parts []input.Input
+
+		for _, output := range outputs {
+			parts = append(parts, client.Input(id, output))
+		}
+
+		res = append(res, input.Concat(parts...))
+	}
+
+	return res, nil
+}
+
+// Client builds a director client from the parsed flags, falling back to the
+// environment variables of the bosh CLI.
+func (f *Flags) Client() (*Client, error) {
+	caCert := firstNonEmpty(f.caCert, os.Getenv("BOSH_CA_CERT"))
+
+	if caCert != "" && !strings.Contains(caCert, "-----BEGIN") {
+		pem, err := ioutil.ReadFile(caCert)
+		if err != nil {
+			return nil, fmt.Errorf("reading director CA certificate: %s", err)
+		}
+
+		caCert = string(pem)
+	}
+
+	return NewClient(ClientOptions{
+		URL:          f.url,
+		CACert:       caCert,
+		Client:       firstNonEmpty(f.client, os.Getenv("BOSH_CLIENT")),
+		ClientSecret: firstNonEmpty(f.clientSecret, os.Getenv("BOSH_CLIENT_SECRET")),
+	})
+}
+
+// Filter builds a task filter from the parsed flags.
+func (f *Flags) Filter() (TaskFilter, error) {
+	filter := TaskFilter{
+		Deployment:   f.deployment,
+		States:       splitList(f.states),
+		Descriptions: splitList(f.descriptions),
+		Types:        splitList(f.types),
+		Limit:        f.limit,
+	}
+
+	if f.since != "" {
+		if d, err := time.ParseDuration(f.since); err == nil {
+			filter.Since = time.Now().Add(-d)
+		} else if t, err := time.Parse(time.RFC3339, f.since); err == nil {
+			filter.Since = t
+		} else {
+			return TaskFilter{}, fmt.Errorf("parsing task since %s: expected a duration or RFC3339 time", f.since)
+		}
+	}
+
+	return filter, nil
+}
+
+func splitList(v string) []string {
+	var res []string
+
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			res = append(res, item)
+		}
+	}
+
+	return res
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,148 @@
+package director
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+)
+
+func resolveFlags(t *testing.T, args ...string) (*Flags, []string) {
+	f := &Flags{}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	f.Register(fs)
+
+	err := fs.Parse(args)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	return f, fs.Args()
+}
+
+func TestFlagsResolve(t *testing.T) {
+	d := newFakeDirector(t, "basic")
+
+	t.Setenv("BOSH_CLIENT", "admin")
+	t.Setenv("BOSH_CLIENT_SECRET", "s3cr3t")
+	t.Setenv("BOSH_CA_CERT", d.caCert())
+
+	tests := []struct {
+		args     []string
+		expected []string
+	}{
+		{
+			args:     []string{"-director", d.URL},
+			expected: []string{"task/1/debug", "task/2/debug", "task/3/debug"},
+		},
+		{
+			args:     []string{"-director", d.URL, "-task-description", "create deployment"},
+			expected: []string{"task/1/debug", "task/3/debug"},
+		},
+		{
+			args:     []string{"-director", d.URL, "-task-since", "2019-06-19T03:30:00Z", "-task-description", "create deployment"},
+			expected: []string{"task/3/debug"},
+		},
+		{
+			args:     []string{"-director", d.URL, "-task-type", "delete"},
+			expected: []string{"task/2/debug"},
+		},
+		{
+			args:     []string{"-director", d.URL, "3", "1"},
+			expected: []string{"task/3/debug", "task/1/debug"},
+		},
+	}
+
+	for _, tt := range tests {
+		f, args := resolveFlags(t, tt.args...)
+
+		inputs, err := f.Resolve(args, DebugOutput)
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %s", tt.args, err)
+		}
+
+		var actual []string
+
+		for _, in := range inputs {
+			actual = append(actual, in.Name)
+		}
+
+		if !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("%v: expected %v, but got %v", tt.args, tt.expected, actual)
+		}
+	}
+}
+
+func TestFlagsResolveInvalid(t *testing.T) {
+	for _, args := range [][]string{
+		{"-director", "https://127.0.0.1:1", "not-an-id"},
+		{"-director", "https://127.0.0.1:1", "-task-since", "yesterday"},
+		{"-director", "https://127.0.0.1:1", "-director-ca-cert", "/does/not/exist"},
+	} {
+		f, rest := resolveFlags(t, args...)
+
+		_, err := f.Resolve(rest, DebugOutput)
+		if err == nil {
+			t.Errorf("%v: expected an error", args)
+		}
+	}
+}
+
+func TestFlagsResolveTaskDirectory(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, name := range []string{"event", "cpi", "debug"} {
+		err := ioutil.WriteFile(filepath.Join(dir, name), []byte(name+"\n"), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		outputs  []OutputType
+		expected []string
+	}{
+		{
+			outputs:  TraceOutputs,
+			expected: []string{"event", "cpi", "debug"},
+		},
+		{
+			outputs:  []OutputType{EventOutput, DebugOutput},
+			expected: []string{"event", "debug"},
+		},
+		{
+			outputs:  []OutputType{DebugOutput},
+			expected: []string{"debug"},
+		},
+	}
+
+	for _, tt := range tests {
+		f, args := resolveFlags(t, dir)
+
+		inputs, err := f.Resolve(args, tt.outputs...)
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %s", tt.outputs, err)
+		} else if len(inputs) != 1 {
+			t.Fatalf("%v: expected 1 input, but got %d", tt.outputs, len(inputs))
+		}
+
+		var actual []string
+
+		err = inputs[0].Scan(func(l log.Line) error {
+			actual = append(actual, l.LineData())
+
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %s", tt.outputs, err)
+		}
+
+		if !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("%v: expected %v, but got %v", tt.outputs, tt.expected, actual)
+		}
+	}
+}
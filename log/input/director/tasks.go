@@ -0,0 +1,174 @@
+package director
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dpb587/bosh-log-tracer/log/input"
+)
+
+// OutputType is one of the outputs the director keeps for a task.
+type OutputType string
+
+const (
+	DebugOutput  OutputType = "debug"
+	EventOutput  OutputType = "event"
+	CPIOutput    OutputType = "cpi"
+	ResultOutput OutputType = "result"
+)
+
+// TraceOutputs are the outputs a task is traced from; the event log is the
+// skeleton of stages which details of the debug log are merged into, and the
+// CPI log adds details to the CPI requests of the debug log.
+var TraceOutputs = []OutputType{EventOutput, CPIOutput, DebugOutput}
+
+// Task is a director task as listed by the API.
+type Task struct {
+	ID          int    `json:"id"`
+	State       string `json:"state"`
+	Description string `json:"description"`
+	Timestamp   int64  `json:"timestamp"`
+	StartedAt   int64  `json:"started_at"`
+	Result      string `json:"result"`
+	User        string `json:"user"`
+	Deployment  string `json:"deployment"`
+	ContextID   string `json:"context_id"`
+}
+
+// TaskFilter selects which tasks are listed. Deployment and States are applied
+// by the director; the rest once tasks were listed.
+type TaskFilter struct {
+	Deployment string
+	States     []string
+
+	// Descriptions match the whole description of a task, ignoring case (e.g.
+	// "create deployment").
+	Descriptions []string
+
+	// Types match the start of the description of a task, ignoring case. Tasks
+	// have no type of their own, but their description begins with what kind of
+	// task it is (e.g. "run errand" of "run errand smoke-tests from deployment
+	// cf").
+	Types []string
+
+	// Since excludes tasks which last changed before it.
+	Since time.Time
+
+	// Limit is the most tasks to list, after filtering; defaults to the
+	// director's limit.
+	Limit int
+}
+
+func (f TaskFilter) query(limit int) url.Values {
+	// all tasks rather than only the ones started by users
+	query := url.Values{"verbose": {"2"}}
+
+	if f.Deployment != "" {
+		query.Set("deployment", f.Deployment)
+	}
+
+	if len(f.States) > 0 {
+		query.Set("state", strings.Join(f.States, ","))
+	}
+
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	return query
+}
+
+func (f TaskFilter) matches(task Task) bool {
+	if !f.Since.IsZero() && time.Unix(task.Timestamp, 0).Before(f.Since) {
+		return false
+	}
+
+	return matchesAny(f.Descriptions, func(description string) bool {
+		return strings.EqualFold(task.Description, description)
+	}) && matchesAny(f.Types, func(taskType string) bool {
+		return len(task.Description) >= len(taskType) && strings.EqualFold(task.Description[:len(taskType)], taskType)
+	})
+}
+
+// matchesAny reports whether any value matches, or true without any.
+func matchesAny(values []string, match func(string) bool) bool {
+	if len(values) == 0 {
+		return true
+	}
+
+	for _, v := range values {
+		if match(v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Tasks lists the tasks matching the filter, most recent first. Since the
+// director only applies part of the filter, more tasks are listed until Limit of
+// them match or there are no more.
+func (c *Client) Tasks(f TaskFilter) ([]Task, error) {
+	limit := f.Limit
+
+	for {
+		tasks, err := c.listTasks(f.query(limit))
+		if err != nil {
+			return nil, err
+		}
+
+		var res []Task
+
+		for _, task := range tasks {
+			if f.Limit > 0 && len(res) == f.Limit {
+				break
+			} else if f.matches(task) {
+				res = append(res, task)
+			}
+		}
+
+		if limit == 0 || len(res) == f.Limit || len(tasks) < limit {
+			return res, nil
+		} else if oldest := tasks[len(tasks)-1]; time.Unix(oldest.Timestamp, 0).Before(f.Since) {
+			// older tasks are assumed to have last changed before it too
+			return res, nil
+		}
+
+		limit *= 2
+	}
+}
+
+func (c *Client) listTasks(query url.Values) ([]Task, error) {
+	path := "/tasks?" + query.Encode()
+
+	body, err := c.get(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var tasks []Task
+
+	err = decodeJSON(path, body, &tasks)
+	if err != nil {
+		return nil, err
+	}
+
+	return tasks, nil
+}
+
+// Output downloads an output of a task.
+func (c *Client) Output(id int, t OutputType) (io.ReadCloser, error) {
+	return c.get(fmt.Sprintf("/tasks/%d/output?type=%s", id, url.QueryEscape(string(t))))
+}
+
+// Input returns an output of a task as an input which is downloaded once it is
+// opened. It is named after the task (e.g. task/1234/debug).
+func (c *Client) Input(id int, t OutputType) input.Input {
+	return input.NewInput(fmt.Sprintf("task/%d/%s", id, t), func() (io.ReadCloser, error) {
+		return c.Output(id, t)
+	})
+}
@@ -0,0 +1,204 @@
+package input
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+)
+
+// Follow returns an input which, like tail -f, waits for more data to be
+// written once the end of the file is reached. Checking for data happens every
+// interval until ctx is done, at which point the rest of the file is read. If
+// set, idle is called whenever the end was reached before waiting. STDIN is
+// returned as-is since it is already read until the writer closes it.
+//
+// Every part of a combined input is followed (e.g. both the event and debug
+// log of a running task). Parts are read in turns, each as far as it was
+// written, so lines of different parts are interleaved; waiting only happens
+// once none of them grew.
+func (i Input) Follow(ctx context.Context, interval time.Duration, idle func() error) (Input, error) {
+	if len(i.parts) > 0 {
+		for _, part := range i.parts {
+			if strings.HasSuffix(part.Name, ".gz") {
+				return Input{}, fmt.Errorf("following %s: compressed inputs cannot be followed", part.Name)
+			}
+		}
+
+		res := i
+		res.follow = &follower{
+			ctx:      ctx,
+			interval: interval,
+			idle:     idle,
+		}
+
+		return res, nil
+	} else if i.Name == Stdin {
+		return i, nil
+	} else if strings.HasSuffix(i.Name, ".gz") {
+		return Input{}, fmt.Errorf("following %s: compressed inputs cannot be followed", i.Name)
+	}
+
+	open := i.open
+
+	return Input{
+		Name: i.Name,
+		open: func() (io.ReadCloser, error) {
+			fh, err := open()
+			if err != nil {
+				return nil, err
+			}
+
+			return followReader{
+				ReadCloser: fh,
+				ctx:        ctx,
+				interval:   interval,
+				idle:       idle,
+			}, nil
+		},
+	}, nil
+}
+
+type followReader struct {
+	io.ReadCloser
+
+	ctx      context.Context
+	interval time.Duration
+	idle     func() error
+}
+
+func (r followReader) Read(p []byte) (int, error) {
+	for {
+		n, err := r.ReadCloser.Read(p)
+		if n > 0 || err != io.EOF {
+			return n, err
+		}
+
+		if r.idle != nil {
+			err := r.idle()
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		timer := time.NewTimer(r.interval)
+
+		select {
+		case <-r.ctx.Done():
+			timer.Stop()
+
+			return 0, io.EOF
+		case <-timer.C:
+		}
+	}
+}
+
+// follower reads the parts of a combined input in turns while following it.
+type follower struct {
+	ctx      context.Context
+	interval time.Duration
+	idle     func() error
+}
+
+type followedPart struct {
+	name    string
+	reader  *bufio.Reader
+	offset  int64
+	pending string
+}
+
+func (f *follower) scan(parts []Input, handler func(log.Line) error) error {
+	var followed []*followedPart
+
+	for _, part := range parts {
+		fh, err := part.Open()
+		if err != nil {
+			return err
+		}
+
+		defer fh.Close()
+
+		followed = append(followed, &followedPart{
+			name:   part.Name,
+			reader: bufio.NewReader(fh),
+		})
+	}
+
+	for {
+		// once following stopped, one more turn reads what was written by then
+		stopped := f.ctx.Err() != nil
+
+		var progressed bool
+
+		for _, part := range followed {
+			read, err := part.scan(stopped, handler)
+			if err != nil {
+				return err
+			}
+
+			progressed = progressed || read
+		}
+
+		if stopped {
+			return nil
+		} else if progressed {
+			continue
+		}
+
+		if f.idle != nil {
+			err := f.idle()
+			if err != nil {
+				return err
+			}
+		}
+
+		timer := time.NewTimer(f.interval)
+
+		select {
+		case <-f.ctx.Done():
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// scan handles the lines of the part written so far. A line without its line
+// break is held until the rest is written, unless final is set.
+func (p *followedPart) scan(final bool, handler func(log.Line) error) (bool, error) {
+	var read bool
+
+	for {
+		data, err := p.reader.ReadString('\n')
+		p.pending += data
+
+		if len(p.pending) > 1024*1024 {
+			return read, fmt.Errorf("reading %s: line too long", p.name)
+		}
+
+		if err == io.EOF {
+			if !final || p.pending == "" {
+				return read, nil
+			}
+		} else if err != nil {
+			return read, fmt.Errorf("reading %s: %s", p.name, err)
+		}
+
+		line := strings.TrimSuffix(strings.TrimSuffix(p.pending, "\n"), "\r")
+		p.pending = ""
+		p.offset++
+		read = true
+
+		err = handler(log.RawLine{
+			RawLineSource: p.name,
+			RawLineOffset: p.offset,
+			RawLineData:   line,
+		})
+		if err != nil {
+			return read, err
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package input
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+)
+
+// Stdin is the name used for an input read from STDIN.
+const Stdin = "-"
+
+type Input struct {
+	Name string
+
+	open func() (io.ReadCloser, error)
+
+	// parts are scanned in order when the input combines several logs
+	parts []Input
+
+	// follow is set when the parts are followed
+	follow *follower
+}
+
+// NewInput returns an input whose content is read from whatever open returns,
+// such as a remote download.
+func NewInput(name string, open func() (io.ReadCloser, error)) Input {
+	return Input{
+		Name: name,
+		open: open,
+	}
+}
+
+// Concat returns an input which scans each part in order, such as the event log
+// of a task before its debug log. Lines are tagged with the name of their part
+// and offsets start at 1 for every part; the input is named after the last.
+func Concat(parts ...Input) Input {
+	if len(parts) == 1 {
+		return parts[0]
+	}
+
+	res := parts[len(parts)-1]
+	res.parts = parts
+
+	return res
+}
+
+func NewFileInput(path string) Input {
+	return Input{
+		Name: path,
+		open: func() (io.ReadCloser, error) {
+			return os.Open(path)
+		},
+	}
+}
+
+func NewStdinInput() Input {
+	return Input{
+		Name: Stdin,
+		open: func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(os.Stdin), nil
+		},
+	}
+}
+
+// Open returns a reader of the input's content, transparently decompressing
+// gzip archives. Of combined inputs, only the last part is opened; use Scan to
+// read every part.
+func (i Input) Open() (io.ReadCloser, error) {
+	fh, err := i.open()
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %s", i.Name, err)
+	}
+
+	if !strings.HasSuffix(i.Name, ".gz") {
+		return fh, nil
+	}
+
+	gz, err := gzip.NewReader(fh)
+	if err != nil {
+		fh.Close()
+
+		return nil, fmt.Errorf("decompressing %s: %s", i.Name, err)
+	}
+
+	return gzipReadCloser{Reader: gz, fh: fh}, nil
+}
+
+// Scan calls handler for each line of the input. Line offsets start at 1 for
+// every input and lines are tagged with the input name as their source.
+func (i Input) Scan(handler func(log.Line) error) error {
+	if i.follow != nil {
+		return i.follow.scan(i.parts, handler)
+	}
+
+	for _, part := range i.parts {
+		err := part.Scan(handler)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(i.parts) > 0 {
+		return nil
+	}
+
+	fh, err := i.Open()
+	if err != nil {
+		return err
+	}
+
+	defer fh.Close()
+
+	var offset int64
+
+	scanner := bufio.NewScanner(fh)
+	buf := make([]byte, 1024*1024)
+	scanner.Buffer(buf, 1024*1024)
+	for scanner.Scan() {
+		offset += 1
+
+		err := handler(log.RawLine{
+			RawLineSource: i.Name,
+			RawLineOffset: offset,
+			RawLineData:   scanner.Text(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("reading %s: %s", i.Name, err)
+	}
+
+	return nil
+}
+
+type gzipReadCloser struct {
+	*gzip.Reader
+
+	fh io.Closer
+}
+
+func (rc gzipReadCloser) Close() error {
+	err := rc.Reader.Close()
+
+	if fhErr := rc.fh.Close(); err == nil {
+		err = fhErr
+	}
+
+	return err
+}
@@ -0,0 +1,110 @@
+package input
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// TaskDirectoryLogs are the file names, in order of preference, which are
+// checked when a director task directory (/var/vcap/store/director/tasks/<id>)
+// is given as an input.
+var TaskDirectoryLogs = []string{"debug", "debug.gz"}
+
+// TaskDirectoryEvents are the file names, in order of preference, of the event
+// log which is read before the debug log of a task directory if it exists.
+var TaskDirectoryEvents = []string{"event", "event.gz"}
+
+// TaskDirectoryCPILogs are the file names, in order of preference, of the CPI
+// log which is read before the debug log of a task directory if it exists.
+var TaskDirectoryCPILogs = []string{"cpi", "cpi.gz"}
+
+// Resolve expands file paths, globs, gzip archives and task directories into
+// inputs. No paths, or a path of "-", reads from STDIN. Task directories combine
+// their event, CPI and debug logs.
+func Resolve(paths ...string) ([]Input, error) {
+	return ResolveTaskLogs([][]string{TaskDirectoryEvents, TaskDirectoryCPILogs}, paths...)
+}
+
+// ResolveTaskLogs is like Resolve, but task directories only combine the logs
+// of parts, each being the file names in order of preference, before their
+// debug log. Without parts, only the debug log is read.
+func ResolveTaskLogs(parts [][]string, paths ...string) ([]Input, error) {
+	if len(paths) == 0 {
+		return []Input{NewStdinInput()}, nil
+	}
+
+	var res []Input
+
+	for _, path := range paths {
+		if path == Stdin {
+			res = append(res, NewStdinInput())
+
+			continue
+		}
+
+		matches := []string{path}
+
+		if strings.ContainsAny(path, "*?[") {
+			var err error
+
+			matches, err = filepath.Glob(path)
+			if err != nil {
+				return nil, fmt.Errorf("expanding %s: %s", path, err)
+			} else if len(matches) == 0 {
+				return nil, fmt.Errorf("expanding %s: no matches", path)
+			}
+		}
+
+		for _, match := range matches {
+			input, err := resolvePath(match, parts)
+			if err != nil {
+				return nil, err
+			}
+
+			res = append(res, input)
+		}
+	}
+
+	return res, nil
+}
+
+func resolvePath(path string, partNames [][]string) (Input, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return Input{}, fmt.Errorf("checking %s: %s", path, err)
+	}
+
+	if !stat.IsDir() {
+		return NewFileInput(path), nil
+	}
+
+	debugPath, found := findFile(path, TaskDirectoryLogs)
+	if !found {
+		return Input{}, fmt.Errorf("checking %s: expected task directory with one of: %s", path, strings.Join(TaskDirectoryLogs, ", "))
+	}
+
+	var parts []Input
+
+	for _, names := range partNames {
+		if partPath, found := findFile(path, names); found {
+			parts = append(parts, NewFileInput(partPath))
+		}
+	}
+
+	return Concat(append(parts, NewFileInput(debugPath))...), nil
+}
+
+// findFile returns the first of names which exists in dir.
+func findFile(dir string, names []string) (string, bool) {
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+
+		if _, err := os.Stat(path); err == nil {
+			return path, true
+		}
+	}
+
+	return "", false
+}
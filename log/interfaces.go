@@ -1,10 +1,45 @@
 package log
 
+// LineParser converts lines into more specific messages. Parsers are expected to
+// be safe for concurrent use unless they are an OrderedParser.
 type LineParser interface {
 	Parse(Line) (Line, error)
 }
 
+// OrderedParser is implemented by parsers which need the lines before a line to
+// complete it (e.g. to infer a missing timestamp). Concurrent pipelines call
+// ParseConcurrently from several goroutines and then ParseOrdered with its
+// results in the order of the lines; Parse does both for a single line.
+type OrderedParser interface {
+	LineParser
+
+	ParseConcurrently(Line) (Line, error)
+	ParseOrdered(Line) (Line, error)
+}
+
+// BufferedParser is implemented by ordered parsers which may hold lines until a
+// later line completes them (e.g. lines before the first timestamp take the
+// time of the next line which has one). Pipelines call ParseBuffered instead of
+// ParseOrdered, which returns the lines now complete in the order of the input,
+// and Drain for the lines still held once the input ended.
+type BufferedParser interface {
+	OrderedParser
+
+	ParseBuffered(Line) ([]Line, error)
+	Drain() ([]Line, error)
+}
+
+// EntryMatcher is implemented by parsers of formats where a single entry may
+// continue over several lines (e.g. backtraces or multi-line SQL).
+type EntryMatcher interface {
+	// IsEntryStart reports whether data begins a new entry rather than
+	// continuing the previous one.
+	IsEntryStart(data string) bool
+}
+
 type Line interface {
+	LineSource() string
 	LineOffset() int64
+	LineEndOffset() int64
 	LineData() string
 }
@@ -0,0 +1,28 @@
+package log
+
+type lenientParser struct {
+	parser      LineParser
+	diagnostics *Diagnostics
+}
+
+var _ LineParser = &lenientParser{}
+
+// NewLenientParser records errors of parser as diagnostics and continues with
+// the line as it was before parser tried to handle it.
+func NewLenientParser(parser LineParser, diagnostics *Diagnostics) LineParser {
+	return &lenientParser{
+		parser:      parser,
+		diagnostics: diagnostics,
+	}
+}
+
+func (lp lenientParser) Parse(in Line) (Line, error) {
+	out, err := lp.parser.Parse(in)
+	if err != nil {
+		lp.diagnostics.Add(NewLineError(in, err))
+
+		return in, nil
+	}
+
+	return out, nil
+}
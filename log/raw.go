@@ -1,16 +1,33 @@
 package log
 
 type RawLine struct {
+	RawLineSource string
 	RawLineOffset int64
 	RawLineData   string
+
+	// RawLineEndOffset is the offset of the last line when continuation lines
+	// were folded into this one; it is zero for a single line.
+	RawLineEndOffset int64
 }
 
 var _ Line = &RawLine{}
 
+func (m RawLine) LineSource() string {
+	return m.RawLineSource
+}
+
 func (m RawLine) LineOffset() int64 {
 	return m.RawLineOffset
 }
 
+func (m RawLine) LineEndOffset() int64 {
+	if m.RawLineEndOffset < m.RawLineOffset {
+		return m.RawLineOffset
+	}
+
+	return m.RawLineEndOffset
+}
+
 func (m RawLine) LineData() string {
 	return m.RawLineData
 }
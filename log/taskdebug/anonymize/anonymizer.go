@@ -0,0 +1,234 @@
+package anonymize
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug"
+	"github.com/dpb587/bosh-log-tracer/redact"
+)
+
+// Anonymizer pseudonymizes identifying values of task log lines. Values are
+// replaced by a keyed hash of themselves, so the same value always results in
+// the same pseudonym for a given salt and lines still correlate with each
+// other.
+type Anonymizer struct {
+	salt    []byte
+	secrets *redact.Redactor
+
+	// names are learned from parsed messages since, unlike IDs, they cannot be
+	// recognized by their format.
+	names   map[string]string
+	namesRE *regexp.Regexp
+
+	pseudonyms map[string]string
+}
+
+func NewAnonymizer(salt string) *Anonymizer {
+	return &Anonymizer{
+		salt: []byte(salt),
+		// SQL literals are left to the other patterns since the tracer
+		// correlates compilation VMs by them.
+		secrets:    redact.NewRedactor(redact.KeyRule{Keys: redact.SensitiveKeys}, redact.URLCredentials),
+		names:      map[string]string{},
+		pseudonyms: map[string]string{},
+	}
+}
+
+// 6056c8c0-2bad-40cf-bcec-4c56f66f12de
+var uuidRE = regexp.MustCompile(`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`)
+
+// i-0abc1234, vol-0123456789abcdef0
+var awsRE = regexp.MustCompile(`\b(ami|eipalloc|eni|i|igw|nat|rtb|sg|snap|subnet|vol|vpc)-([0-9a-f]{1,17})\b`)
+
+// web/6318b9e7-8c72-4c4e-8769-e59abaa32297 (0)
+var instanceRE = regexp.MustCompile(`([A-Za-z0-9_\-]+)/[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12} \(\d+\)`)
+
+// 10.0.16.5
+var ipRE = regexp.MustCompile(`\b(\d{1,3})\.(\d{1,3})\.(\d{1,3})\.(\d{1,3})\b`)
+
+// Learn records the deployment names, instance group names and agent IDs
+// mentioned by a parsed message.
+func (a *Anonymizer) Learn(msg log.Line) {
+	switch m := msg.(type) {
+	case taskdebug.TaskMessage:
+		a.learn("deployment", m.DeploymentName)
+	case taskdebug.LockMessage:
+		if strings.HasPrefix(m.Name, "lock:deployment:") {
+			a.learn("deployment", strings.TrimPrefix(m.Name, "lock:deployment:"))
+		}
+	case taskdebug.InstanceAspectChangedMessage:
+		a.learn("instance-group", m.InstanceGroup)
+	case taskdebug.NATSMessageSentAgentMessage:
+		a.learn("agent", m.AgentID)
+	case taskdebug.ExternalCPIRequestMessage:
+		if m.PayloadMethod == "create_vm" {
+			if args, err := m.GetArguments(); err == nil && len(args) > 0 {
+				if agentID, ok := args[0].(string); ok {
+					a.learn("agent", agentID)
+				}
+			}
+		}
+	}
+
+	if raw, ok := msg.(taskdebug.RawMessageGetter); ok {
+		a.learn("instance-group", raw.GetRawMessage().Tags["instance_group"])
+	}
+
+	// instances are mentioned by many messages which are not parsed (e.g.
+	// Deleting instance 'web/6318b9e7-8c72-4c4e-8769-e59abaa32297 (0)')
+	for _, m := range instanceRE.FindAllStringSubmatch(msg.LineData(), -1) {
+		a.learn("instance-group", m[1])
+	}
+}
+
+func (a *Anonymizer) learn(kind, name string) {
+	if name == "" {
+		return
+	} else if _, known := a.names[name]; known {
+		return
+	} else if uuidRE.MatchString(name) || awsRE.MatchString(name) || ipRE.MatchString(name) {
+		// already covered by the patterns (e.g. compilation-5c8a...)
+		return
+	}
+
+	a.names[name] = kind
+	a.namesRE = nil
+}
+
+// Line returns the anonymized data of a line.
+func (a *Anonymizer) Line(data string) string {
+	data = a.secrets.String(data)
+	data = a.replaceNames(data)
+
+	data = uuidRE.ReplaceAllStringFunc(data, a.uuid)
+	data = awsRE.ReplaceAllStringFunc(data, a.awsID)
+	data = ipRE.ReplaceAllStringFunc(data, a.ip)
+
+	return data
+}
+
+func (a *Anonymizer) replaceNames(data string) string {
+	if len(a.names) == 0 {
+		return data
+	}
+
+	if a.namesRE == nil {
+		var names []string
+
+		for name := range a.names {
+			names = append(names, regexp.QuoteMeta(name))
+		}
+
+		// prefer the longest name when one contains another
+		sort.Slice(names, func(i, j int) bool {
+			if len(names[i]) != len(names[j]) {
+				return len(names[i]) > len(names[j])
+			}
+
+			return names[i] < names[j]
+		})
+
+		a.namesRE = regexp.MustCompile(strings.Join(names, "|"))
+	}
+
+	var res strings.Builder
+
+	var last int
+
+	for _, match := range a.namesRE.FindAllStringIndex(data, -1) {
+		start, end := match[0], match[1]
+
+		before, after := byte(' '), byte(' ')
+		if start > 0 {
+			before = data[start-1]
+		}
+
+		if end < len(data) {
+			after = data[end]
+		}
+
+		if isNameByte(before) || isNameByte(after) {
+			// only whole names (e.g. not "web" of "webapp")
+			continue
+		} else if before == ' ' && after == ' ' {
+			// names are quoted or part of a path; a bare word is more likely
+			// prose (e.g. "worker" of "Running from worker 'worker_4'")
+			continue
+		}
+
+		name := data[start:end]
+
+		res.WriteString(data[last:start])
+		res.WriteString(a.pseudonym(name, func(sum string) string {
+			return fmt.Sprintf("%s-%s", a.names[name], sum[0:8])
+		}))
+		last = end
+	}
+
+	res.WriteString(data[last:])
+
+	return res.String()
+}
+
+func isNameByte(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9' || b == '_' || b == '-'
+}
+
+func (a *Anonymizer) uuid(value string) string {
+	return a.pseudonym(strings.ToLower(value), func(sum string) string {
+		return fmt.Sprintf("%s-%s-%s-%s-%s", sum[0:8], sum[8:12], sum[12:16], sum[16:20], sum[20:32])
+	})
+}
+
+func (a *Anonymizer) awsID(value string) string {
+	m := awsRE.FindStringSubmatch(value)
+
+	return a.pseudonym(value, func(sum string) string {
+		return fmt.Sprintf("%s-%s", m[1], sum[0:len(m[2])])
+	})
+}
+
+func (a *Anonymizer) ip(value string) string {
+	m := ipRE.FindStringSubmatch(value)
+
+	for _, octet := range m[1:] {
+		if v, _ := strconv.Atoi(octet); v > 255 {
+			// probably a version rather than an address
+			return value
+		}
+	}
+
+	if m[1] == "127" || value == "0.0.0.0" {
+		return value
+	}
+
+	return a.pseudonym(value, func(sum string) string {
+		b, _ := hex.DecodeString(sum[0:6])
+
+		return fmt.Sprintf("10.%d.%d.%d", b[0], b[1], 1+int(b[2])%254)
+	})
+}
+
+// pseudonym returns the replacement of value, formatting the hex-encoded
+// hash of new values.
+func (a *Anonymizer) pseudonym(value string, format func(sum string) string) string {
+	if res, found := a.pseudonyms[value]; found {
+		return res
+	}
+
+	mac := hmac.New(sha256.New, a.salt)
+	mac.Write([]byte(value))
+
+	res := format(hex.EncodeToString(mac.Sum(nil)))
+	a.pseudonyms[value] = res
+
+	return res
+}
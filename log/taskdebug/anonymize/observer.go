@@ -0,0 +1,61 @@
+package anonymize
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/observer"
+)
+
+// Observer writes the anonymized lines of a task once it has been committed.
+// Lines are held until then since names may only be learned from later lines.
+type Observer struct {
+	anonymizer *Anonymizer
+	writer     io.Writer
+
+	lines []string
+}
+
+var _ observer.Observer = &Observer{}
+
+// NewObserver returns an observer writing to w; the anonymizer may be shared
+// between observers so several logs use the same pseudonyms.
+func NewObserver(anonymizer *Anonymizer, w io.Writer) *Observer {
+	return &Observer{
+		anonymizer: anonymizer,
+		writer:     w,
+	}
+}
+
+func (l *Observer) Begin() error {
+	l.lines = nil
+
+	return nil
+}
+
+func (l *Observer) Handle(msg log.Line) error {
+	l.anonymizer.Learn(msg)
+	l.lines = append(l.lines, msg.LineData())
+
+	return nil
+}
+
+// Flush does nothing since names learned from later lines may still apply to
+// the lines held so far.
+func (l *Observer) Flush() error {
+	return nil
+}
+
+func (l *Observer) Commit() error {
+	for _, line := range l.lines {
+		_, err := fmt.Fprintln(l.writer, l.anonymizer.Line(line))
+		if err != nil {
+			return fmt.Errorf("writing anonymized line: %s", err)
+		}
+	}
+
+	l.lines = nil
+
+	return nil
+}
@@ -0,0 +1,161 @@
+package anonymize
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dpb587/bosh-log-tracer/log/input"
+	"github.com/dpb587/bosh-log-tracer/log/input/director"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug/parser"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug/timeline"
+	"github.com/dpb587/bosh-log-tracer/observer/context"
+	"github.com/dpb587/bosh-log-tracer/pipeline"
+	"github.com/dpb587/bosh-log-tracer/trace"
+)
+
+// traceShape describes the span tree of a log without the values which are
+// anonymized (e.g. names and IDs of operations and tags).
+func traceShape(t *testing.T, in input.Input) string {
+	t.Helper()
+
+	obs := timeline.NewObserver(&context.Context{}, timeline.ObserverOptions{})
+
+	err := pipeline.Run(in, parser.NewParser(parser.Options{}), obs)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	depths := map[trace.SpanID]int{}
+
+	var res strings.Builder
+
+	for _, sp := range obs.Trace().FinishedSpans() {
+		if sp.ParentID != 0 {
+			depths[sp.ID] = depths[sp.ParentID] + 1
+		}
+
+		fmt.Fprintf(&res, "%s%s %s +%s tags=%d events=%d error=%t\n", strings.Repeat("  ", depths[sp.ID]), sp.Service, sp.StartTime.Format("15:04:05.000000"), sp.Duration(), len(sp.Tags), len(sp.Events), sp.IsError())
+	}
+
+	return res.String()
+}
+
+func TestObserverTracesTheSame(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("..", "testdata", "*.log"))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(paths) == 0 {
+		t.Fatal("expected fixtures")
+	}
+
+	for _, path := range paths {
+		t.Run(strings.TrimSuffix(filepath.Base(path), ".log"), func(t *testing.T) {
+			original, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			buf := &bytes.Buffer{}
+
+			err = pipeline.Run(input.NewFileInput(path), parser.NewParser(parser.Options{}), NewObserver(NewAnonymizer("salt"), buf))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			anonymized := buf.Bytes()
+
+			if bytes.Equal(original, anonymized) {
+				t.Fatalf("expected the log to be anonymized")
+			} else if expected, actual := bytes.Count(original, []byte("\n")), bytes.Count(anonymized, []byte("\n")); expected != actual {
+				t.Errorf("expected %d lines, but got %d", expected, actual)
+			}
+
+			for _, leaked := range []string{"deployment-6412ca06", "2184b994-fddd-fc27-99c8-76b183cdbc27", "10.87.208.173"} {
+				if bytes.Contains(original, []byte(leaked)) && bytes.Contains(anonymized, []byte(leaked)) {
+					t.Errorf("expected %s to be anonymized", leaked)
+				}
+			}
+
+			expected := traceShape(t, input.NewFileInput(path))
+			actual := traceShape(t, input.NewInput("anonymized", func() (io.ReadCloser, error) {
+				return ioutil.NopCloser(bytes.NewReader(anonymized)), nil
+			}))
+
+			if expected != actual {
+				t.Errorf("expected the same span tree\nexpected:\n%s\nactual:\n%s", expected, actual)
+			}
+		})
+	}
+}
+
+func TestObserverTaskDirectory(t *testing.T) {
+	dir := t.TempDir()
+
+	for name, fixture := range map[string]string{
+		"event": "deploy-compilation.event",
+		"cpi":   "deploy-compilation.cpi",
+		"debug": "deploy-compilation.log",
+	} {
+		data, err := ioutil.ReadFile(filepath.Join("..", "testdata", fixture))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = ioutil.WriteFile(filepath.Join(dir, name), data, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	inputs, err := (&director.Flags{}).Resolve([]string{dir}, director.DebugOutput)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if len(inputs) != 1 {
+		t.Fatalf("expected 1 input, but got %d", len(inputs))
+	}
+
+	buf := &bytes.Buffer{}
+
+	err = pipeline.Run(inputs[0], parser.NewParser(parser.Options{}), NewObserver(NewAnonymizer("salt"), buf))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	original, err := ioutil.ReadFile(filepath.Join("..", "testdata", "deploy-compilation.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, actual := bytes.Count(original, []byte("\n")), bytes.Count(buf.Bytes(), []byte("\n")); expected != actual {
+		t.Errorf("expected the %d lines of the debug log, but got %d", expected, actual)
+	}
+
+	originalLines := strings.Split(string(original), "\n")
+
+	for idx, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		if idx >= len(originalLines) {
+			break
+		}
+
+		// the header of every line, up to its process ID, is kept as is
+		expected := strings.SplitN(originalLines[idx], "]", 2)[0]
+
+		if !strings.HasPrefix(line, expected) {
+			t.Errorf("expected line %d to start with %s, but got %s", idx+1, expected, line)
+		}
+	}
+
+	expected := traceShape(t, input.NewFileInput(filepath.Join("..", "testdata", "deploy-compilation.log")))
+	actual := traceShape(t, input.NewInput("anonymized", func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(buf.Bytes())), nil
+	}))
+
+	if expected != actual {
+		t.Errorf("expected the span tree of the debug log\nexpected:\n%s\nactual:\n%s", expected, actual)
+	}
+}
@@ -0,0 +1,16 @@
+package taskdebug
+
+import (
+	"github.com/dpb587/bosh-log-tracer/log"
+)
+
+// ArtifactStepMessage is a step of uploading a release or stemcell tarball.
+type ArtifactStepMessage struct {
+	RawMessage
+
+	Artifact string
+	Step     string
+	Detail   string
+}
+
+var _ log.Line = &ArtifactStepMessage{}
@@ -0,0 +1,18 @@
+package taskdebug
+
+import (
+	"time"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+)
+
+type BlobstoreMessage struct {
+	RawMessage
+
+	Action   string
+	ObjectID string
+	Event    string
+	Duration time.Duration
+}
+
+var _ log.Line = &BlobstoreMessage{}
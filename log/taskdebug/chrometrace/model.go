@@ -0,0 +1,196 @@
+package chrometrace
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/dpb587/bosh-log-tracer/trace"
+)
+
+// Services are given threads in this order so tracks are laid out similarly
+// between traces; other services are added after them as they are seen.
+var Services = []string{
+	"worker",
+	"stage",
+	"compiler",
+	"creator",
+	"updater",
+	"nats",
+	"cpi",
+	"aws",
+	"lock",
+	"db",
+}
+
+// Event is a single entry of the Trace Event Format.
+// See https://docs.google.com/document/d/1CvAClvFfyA5R-PhYUmn5OOQtYMH4h6I0nSsKchNAySU
+type Event struct {
+	Name      string                 `json:"name"`
+	Category  string                 `json:"cat,omitempty"`
+	Phase     string                 `json:"ph"`
+	Timestamp int64                  `json:"ts"`
+	Duration  *int64                 `json:"dur,omitempty"`
+	ProcessID int                    `json:"pid"`
+	ThreadID  int                    `json:"tid"`
+	Color     string                 `json:"cname,omitempty"`
+	Args      map[string]interface{} `json:"args,omitempty"`
+}
+
+const (
+	PhaseComplete = "X"
+	PhaseMetadata = "M"
+)
+
+// Document collects the events of one or more traces; each trace is its own
+// process.
+type Document struct {
+	TraceEvents     []Event `json:"traceEvents"`
+	DisplayTimeUnit string  `json:"displayTimeUnit"`
+
+	processes int
+}
+
+func NewDocument() *Document {
+	return &Document{
+		TraceEvents:     []Event{},
+		DisplayTimeUnit: "ms",
+	}
+}
+
+// AddTrace adds the finished spans of a trace as a new process.
+func (d *Document) AddTrace(name string, tr *trace.Trace) {
+	d.processes++
+	pid := d.processes
+
+	d.TraceEvents = append(d.TraceEvents, Event{
+		Name:      "process_name",
+		Phase:     PhaseMetadata,
+		ProcessID: pid,
+		Args:      map[string]interface{}{"name": name},
+	})
+
+	threads := map[string]int{}
+
+	for idx, service := range Services {
+		threads[service] = idx + 1
+	}
+
+	var events []Event
+	var usedThreads []string
+
+	for _, span := range tr.FinishedSpans() {
+		service := span.Service
+
+		tid, found := threads[service]
+		if !found {
+			tid = len(threads) + 1
+			threads[service] = tid
+		}
+
+		if !containsString(usedThreads, service) {
+			usedThreads = append(usedThreads, service)
+		}
+
+		events = append(events, newEvent(pid, tid, span))
+	}
+
+	sort.Slice(usedThreads, func(i, j int) bool {
+		return threads[usedThreads[i]] < threads[usedThreads[j]]
+	})
+
+	for _, service := range usedThreads {
+		d.TraceEvents = append(
+			d.TraceEvents,
+			Event{
+				Name:      "thread_name",
+				Phase:     PhaseMetadata,
+				ProcessID: pid,
+				ThreadID:  threads[service],
+				Args:      map[string]interface{}{"name": service},
+			},
+			Event{
+				Name:      "thread_sort_index",
+				Phase:     PhaseMetadata,
+				ProcessID: pid,
+				ThreadID:  threads[service],
+				Args:      map[string]interface{}{"sort_index": threads[service]},
+			},
+		)
+	}
+
+	// longer spans first for the same start so parents enclose their children
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].Timestamp != events[j].Timestamp {
+			return events[i].Timestamp < events[j].Timestamp
+		}
+
+		return *events[i].Duration > *events[j].Duration
+	})
+
+	d.TraceEvents = append(d.TraceEvents, events...)
+}
+
+func (d *Document) Write(w io.Writer) error {
+	return json.NewEncoder(w).Encode(d)
+}
+
+func newEvent(pid, tid int, span *trace.Span) Event {
+	duration := toMicroseconds(span.FinishTime) - toMicroseconds(span.StartTime)
+
+	event := Event{
+		Name:      span.OperationName,
+		Category:  span.Service,
+		Phase:     PhaseComplete,
+		Timestamp: toMicroseconds(span.StartTime),
+		Duration:  &duration,
+		ProcessID: pid,
+		ThreadID:  tid,
+		Args:      map[string]interface{}{},
+	}
+
+	for _, tag := range span.Tags {
+		event.Args[tag.Key] = tag.ScalarValue()
+	}
+
+	if span.IsError() {
+		event.Color = "terrible"
+		event.Args["error"] = span.Status.Message
+	}
+
+	var logs []map[string]interface{}
+
+	for _, e := range span.Events {
+		fields := map[string]interface{}{
+			"ts":    toMicroseconds(e.Time),
+			"event": e.Name,
+		}
+
+		for _, field := range e.Fields {
+			fields[field.Key] = field.ScalarValue()
+		}
+
+		logs = append(logs, fields)
+	}
+
+	if len(logs) > 0 {
+		event.Args["logs"] = logs
+	}
+
+	return event
+}
+
+func toMicroseconds(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Microsecond)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
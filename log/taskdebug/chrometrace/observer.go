@@ -0,0 +1,23 @@
+package chrometrace
+
+import (
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug/timeline"
+	"github.com/dpb587/bosh-log-tracer/observer/context"
+)
+
+type ObserverOptions struct {
+	Timeline timeline.ObserverOptions
+
+	// Document receives the trace; it may be shared between observers to
+	// combine several tasks.
+	Document *Document
+
+	// Name labels the trace's process; defaults to the task.
+	Name string
+}
+
+// NewObserver adds the timeline of a task to a Trace Event Format document
+// once it has been committed.
+func NewObserver(ctx *context.Context, o ObserverOptions) *timeline.Collector {
+	return timeline.NewCollector(ctx, o.Timeline, o.Document, o.Name)
+}
@@ -0,0 +1,301 @@
+package chrometrace
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dpb587/bosh-log-tracer/log/input"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug/parser"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug/timeline"
+	"github.com/dpb587/bosh-log-tracer/observer/context"
+	"github.com/dpb587/bosh-log-tracer/pipeline"
+	"github.com/dpb587/bosh-log-tracer/trace"
+)
+
+// fixtureInput reads the event and CPI logs of a fixture, if any, before its
+// debug log like a task directory.
+func fixtureInput(t *testing.T, path string) input.Input {
+	t.Helper()
+
+	var parts []input.Input
+
+	for _, ext := range []string{".event", ".cpi"} {
+		part := strings.TrimSuffix(path, ".log") + ext
+
+		if _, err := os.Stat(part); err == nil {
+			parts = append(parts, input.NewFileInput(part))
+		} else if !os.IsNotExist(err) {
+			t.Fatal(err)
+		}
+	}
+
+	return input.Concat(append(parts, input.NewFileInput(path))...)
+}
+
+// decodedEvent is an event as read by trace viewers.
+type decodedEvent struct {
+	Name      string                 `json:"name"`
+	Category  string                 `json:"cat"`
+	Phase     string                 `json:"ph"`
+	Timestamp int64                  `json:"ts"`
+	Duration  *int64                 `json:"dur"`
+	ProcessID int                    `json:"pid"`
+	ThreadID  int                    `json:"tid"`
+	Args      map[string]interface{} `json:"args"`
+}
+
+func TestObserverDocument(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("..", "testdata", "*.log"))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(paths) == 0 {
+		t.Fatal("expected fixtures")
+	}
+
+	doc := NewDocument()
+
+	// the spans each process is expected to have, as complete events
+	var expectedSpans [][]string
+
+	for _, path := range paths {
+		opts := timeline.ObserverOptions{IncludeLogReferences: true}
+
+		err := pipeline.Run(fixtureInput(t, path), parser.NewParser(parser.Options{}), NewObserver(&context.Context{}, ObserverOptions{
+			Timeline: opts,
+			Document: doc,
+			Name:     filepath.Base(path),
+		}))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", path, err)
+		}
+
+		obs := timeline.NewObserver(&context.Context{}, opts)
+
+		err = pipeline.Run(fixtureInput(t, path), parser.NewParser(parser.Options{}), obs)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", path, err)
+		}
+
+		var spans []string
+
+		for _, sp := range obs.Trace().FinishedSpans() {
+			spans = append(spans, fmt.Sprintf("%s %s @%d +%d", sp.Service, sp.OperationName, toMicroseconds(sp.StartTime), toMicroseconds(sp.FinishTime)-toMicroseconds(sp.StartTime)))
+		}
+
+		sort.Strings(spans)
+
+		expectedSpans = append(expectedSpans, spans)
+	}
+
+	buf := &bytes.Buffer{}
+
+	err = doc.Write(buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var decoded struct {
+		TraceEvents     []decodedEvent `json:"traceEvents"`
+		DisplayTimeUnit string         `json:"displayTimeUnit"`
+	}
+
+	err = json.Unmarshal(buf.Bytes(), &decoded)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	processes := map[int]string{}
+	threads := map[int]map[int]string{}
+	sortIndexes := map[int]map[int]bool{}
+	spans := map[int][]string{}
+	references := map[int]int{}
+	seen := map[string]bool{}
+
+	var lastTimestamp int64
+
+	for _, e := range decoded.TraceEvents {
+		switch {
+		case e.Phase == PhaseMetadata && e.Name == "process_name":
+			processes[e.ProcessID] = e.Args["name"].(string)
+			threads[e.ProcessID] = map[int]string{}
+			sortIndexes[e.ProcessID] = map[int]bool{}
+			lastTimestamp = 0
+		case e.Phase == PhaseMetadata && e.Name == "thread_name":
+			service := e.Args["name"].(string)
+
+			for tid, other := range threads[e.ProcessID] {
+				if other == service {
+					t.Errorf("%d: expected one thread for %s, but got %d and %d", e.ProcessID, service, tid, e.ThreadID)
+				}
+			}
+
+			threads[e.ProcessID][e.ThreadID] = service
+			seen[service] = true
+		case e.Phase == PhaseMetadata && e.Name == "thread_sort_index":
+			sortIndexes[e.ProcessID][e.ThreadID] = true
+
+			if index := e.Args["sort_index"].(float64); int(index) != e.ThreadID {
+				t.Errorf("%d: expected thread %d to be sorted as such, but got %v", e.ProcessID, e.ThreadID, index)
+			}
+		case e.Phase == PhaseComplete:
+			if service, found := threads[e.ProcessID][e.ThreadID]; !found {
+				t.Errorf("%d: %s: expected thread %d to be named before its events", e.ProcessID, e.Name, e.ThreadID)
+			} else if service != e.Category {
+				t.Errorf("%d: %s: expected to be on the thread of %s, but got %s", e.ProcessID, e.Name, e.Category, service)
+			}
+
+			if e.Duration == nil {
+				t.Errorf("%d: %s: expected a duration", e.ProcessID, e.Name)
+
+				continue
+			} else if e.Timestamp < lastTimestamp {
+				t.Errorf("%d: %s: expected events in order of their start, but got %d after %d", e.ProcessID, e.Name, e.Timestamp, lastTimestamp)
+			}
+
+			lastTimestamp = e.Timestamp
+
+			spans[e.ProcessID] = append(spans[e.ProcessID], fmt.Sprintf("%s %s @%d +%d", e.Category, e.Name, e.Timestamp, *e.Duration))
+
+			if logs, found := e.Args["logs"]; found {
+				for _, l := range logs.([]interface{}) {
+					fields := l.(map[string]interface{})
+
+					if _, reference := fields["line"]; !reference {
+						// e.g. the error of a span
+						continue
+					}
+
+					if line, ok := fields["line"].(float64); !ok || line < 1 {
+						t.Errorf("%d: %s: expected a log reference to its line, but got %v", e.ProcessID, e.Name, fields)
+					} else if _, ok := fields["message"].(string); !ok {
+						t.Errorf("%d: %s: expected a log reference to its message, but got %v", e.ProcessID, e.Name, fields)
+					} else if _, ok := fields["ts"].(float64); !ok {
+						t.Errorf("%d: %s: expected a log reference to its time, but got %v", e.ProcessID, e.Name, fields)
+					}
+
+					references[e.ProcessID]++
+				}
+			}
+		default:
+			t.Errorf("%d: unexpected event %s (%s)", e.ProcessID, e.Name, e.Phase)
+		}
+	}
+
+	if decoded.DisplayTimeUnit != "ms" {
+		t.Errorf("expected ms, but got %s", decoded.DisplayTimeUnit)
+	} else if len(processes) != len(paths) {
+		t.Fatalf("expected a process for each of the %d traces, but got %d", len(paths), len(processes))
+	}
+
+	for pid := 1; pid <= len(paths); pid++ {
+		if name := filepath.Base(paths[pid-1]); processes[pid] != name {
+			t.Errorf("%d: expected process %s, but got %s", pid, name, processes[pid])
+		}
+
+		for tid, service := range threads[pid] {
+			if !sortIndexes[pid][tid] {
+				t.Errorf("%d: expected a sort index for %s", pid, service)
+			}
+
+			for idx, known := range Services {
+				if known == service && tid != idx+1 {
+					t.Errorf("%d: expected %s to be thread %d, but got %d", pid, service, idx+1, tid)
+				}
+			}
+		}
+
+		sort.Strings(spans[pid])
+
+		if expected, actual := strings.Join(expectedSpans[pid-1], "\n"), strings.Join(spans[pid], "\n"); expected != actual {
+			t.Errorf("%d: expected the spans of the trace\nexpected:\n%s\nactual:\n%s", pid, expected, actual)
+		}
+
+		if references[pid] == 0 {
+			t.Errorf("%d: expected log references", pid)
+		}
+	}
+
+	// queries are not traced from logs, so db is covered by TestDocumentAddTrace
+	for _, service := range []string{"worker", "stage", "compiler", "updater", "nats", "cpi", "aws", "lock"} {
+		if !seen[service] {
+			t.Errorf("expected a thread for %s", service)
+		}
+	}
+}
+
+func TestDocumentAddTrace(t *testing.T) {
+	t0 := time.Date(2019, 6, 19, 1, 44, 52, 0, time.UTC)
+
+	tr := trace.NewTrace(nil)
+
+	task := tr.StartSpan("worker", "task", nil, t0)
+
+	for idx, service := range append([]string{"custom"}, Services[1:]...) {
+		sp := tr.StartSpan(service, "op-"+service, task, t0.Add(time.Duration(idx+1)*time.Millisecond))
+		sp.Finish(sp.StartTime.Add(1500 * time.Microsecond))
+	}
+
+	failed := tr.StartSpan("cpi", "create_vm", task, t0.Add(time.Second))
+	failed.AddEvent(t0.Add(1100*time.Millisecond), "start", trace.Tag{Key: "line", Value: int64(42)})
+	failed.SetError(t0.Add(1200*time.Millisecond), "Bosh::Clouds::VMCreationFailed", "failed")
+	failed.Finish(t0.Add(1200 * time.Millisecond))
+
+	tr.StartSpan("db", "unfinished", task, t0)
+
+	task.Finish(t0.Add(2 * time.Second))
+
+	doc := NewDocument()
+	doc.AddTrace("test", tr)
+
+	threads := map[string]int{}
+	complete := map[string]Event{}
+
+	for _, e := range doc.TraceEvents {
+		if e.Phase == PhaseMetadata && e.Name == "thread_name" {
+			threads[e.Args["name"].(string)] = e.ThreadID
+		} else if e.Phase == PhaseComplete {
+			complete[e.Name] = e
+		}
+	}
+
+	for idx, service := range Services {
+		if threads[service] != idx+1 {
+			t.Errorf("expected %s to be thread %d, but got %d", service, idx+1, threads[service])
+		}
+	}
+
+	if tid := threads["custom"]; tid != len(Services)+1 {
+		t.Errorf("expected other services after the known ones, but got thread %d", tid)
+	} else if len(threads) != len(Services)+1 {
+		t.Errorf("expected a thread for each service, but got %v", threads)
+	}
+
+	if _, found := complete["unfinished"]; found {
+		t.Errorf("expected unfinished spans to be skipped")
+	}
+
+	start := t0.UnixNano() / int64(time.Microsecond)
+
+	if e := complete["task"]; e.Timestamp != start || *e.Duration != 2000000 || e.ThreadID != 1 {
+		t.Errorf("unexpected task: %+v", e)
+	} else if e := complete["op-db"]; e.Category != "db" || e.Timestamp != start+int64(len(Services))*1000 || *e.Duration != 1500 || e.ThreadID != threads["db"] {
+		t.Errorf("unexpected query: %+v (%d)", e, *e.Duration)
+	}
+
+	e := complete["create_vm"]
+
+	logs, _ := e.Args["logs"].([]map[string]interface{})
+
+	if e.Color != "terrible" || e.Args["error"] != "failed" || *e.Duration != 200000 {
+		t.Errorf("unexpected failure: %+v", e)
+	} else if len(logs) != 2 || logs[0]["event"] != "start" || logs[0]["line"] != int64(42) || logs[0]["ts"] != start+1100000 {
+		t.Errorf("unexpected logs: %+v", logs)
+	}
+}
@@ -0,0 +1,32 @@
+package taskdebug
+
+import (
+	"time"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+)
+
+// CPILogMessage is a line logged by the CPI itself (e.g. the task's cpi log),
+// correlated with the external CPI request it was logged during.
+type CPILogMessage struct {
+	RawMessage
+
+	Correlation string
+}
+
+var _ log.Line = &CPILogMessage{}
+
+// CPIWaitMessage is logged by the CPI while it waits for an IaaS resource to
+// reach a state.
+type CPIWaitMessage struct {
+	CPILogMessage
+
+	Event    string
+	Resource string
+	State    string
+
+	// Duration is only known once the wait finished.
+	Duration time.Duration
+}
+
+var _ log.Line = &CPIWaitMessage{}
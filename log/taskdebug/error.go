@@ -0,0 +1,15 @@
+package taskdebug
+
+import (
+	"github.com/dpb587/bosh-log-tracer/log"
+)
+
+type ErrorMessage struct {
+	RawMessage
+
+	ErrorType    string
+	ErrorMessage string
+	Backtrace    []string
+}
+
+var _ log.Line = &ErrorMessage{}
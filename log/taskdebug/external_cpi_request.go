@@ -1,6 +1,11 @@
 package taskdebug
 
-import "github.com/dpb587/bosh-log-tracer/log"
+import (
+	"encoding/json"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/pkg/errors"
+)
 
 type ExternalCPIRequestMessage struct {
 	ExternalCPIMessage
@@ -11,3 +16,16 @@ type ExternalCPIRequestMessage struct {
 }
 
 var _ log.Line = &ExternalCPIRequestMessage{}
+
+func (m ExternalCPIRequestMessage) GetArguments() ([]interface{}, error) {
+	var payload struct {
+		Arguments []interface{} `json:"arguments"`
+	}
+
+	err := json.Unmarshal([]byte(m.Payload), &payload)
+	if err != nil {
+		return nil, errors.Wrap(err, "unmarshaling external cpi request payload")
+	}
+
+	return payload.Arguments, nil
+}
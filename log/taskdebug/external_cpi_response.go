@@ -0,0 +1,46 @@
+package taskdebug
+
+import (
+	"github.com/dpb587/bosh-log-tracer/log"
+)
+
+type ExternalCPIResponseMessage struct {
+	ExternalCPIMessage
+
+	Payload string
+
+	// Result is whatever the method returned (e.g. the CID of a created VM).
+	Result interface{}
+
+	// ErrorType is set when the CPI failed (e.g. Bosh::Clouds::VMCreationFailed).
+	ErrorType      string
+	ErrorMessage   string
+	ErrorOkToRetry bool
+
+	// Log is what the CPI logged while handling the request, which the
+	// director also appends to the task's cpi log.
+	Log string
+
+	Stderr     string
+	ExitStatus string
+}
+
+var _ log.Line = &ExternalCPIResponseMessage{}
+
+// GetResultCID returns the CID of the result, which is either the result
+// itself or, for methods returning more details (e.g. create_vm of CPI API v2),
+// its first element.
+func (m ExternalCPIResponseMessage) GetResultCID() (string, bool) {
+	switch v := m.Result.(type) {
+	case string:
+		return v, v != ""
+	case []interface{}:
+		if len(v) > 0 {
+			cid, ok := v[0].(string)
+
+			return cid, ok && cid != ""
+		}
+	}
+
+	return "", false
+}
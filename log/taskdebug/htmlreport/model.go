@@ -0,0 +1,196 @@
+package htmlreport
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dpb587/bosh-log-tracer/trace"
+)
+
+// Report is a collection of task timelines rendered into a single page.
+type Report struct {
+	Traces []Trace
+}
+
+type Trace struct {
+	Name     string
+	Start    time.Time
+	Duration time.Duration
+	Error    bool
+	Rows     []Row
+}
+
+// Row is a span placed on the timeline; rows are ordered so children follow
+// their parent.
+type Row struct {
+	ID        string
+	Depth     int
+	Service   string
+	Operation string
+	Offset    time.Duration
+	Duration  time.Duration
+
+	// Left and Width are percentages of the trace duration.
+	Left  float64
+	Width float64
+
+	Error      bool
+	Incomplete bool
+	Tags       []Tag
+	Logs       []Log
+}
+
+type Tag struct {
+	Key   string
+	Value string
+}
+
+type Log struct {
+	Offset  time.Duration
+	Event   string
+	Source  string
+	Line    int64
+	EndLine int64
+	Message string
+}
+
+func (r *Report) AddTrace(name string, tr *trace.Trace) {
+	r.Traces = append(r.Traces, NewTrace(name, tr))
+}
+
+// NewTrace lays out the finished spans of a trace.
+func NewTrace(name string, tr *trace.Trace) Trace {
+	res := Trace{Name: name}
+
+	spans := tr.FinishedSpans()
+	if len(spans) == 0 {
+		return res
+	}
+
+	children := map[trace.SpanID][]*trace.Span{}
+	known := map[trace.SpanID]bool{}
+
+	var start, end time.Time
+
+	for idx, span := range spans {
+		known[span.ID] = true
+
+		if idx == 0 || span.StartTime.Before(start) {
+			start = span.StartTime
+		}
+
+		if idx == 0 || span.FinishTime.After(end) {
+			end = span.FinishTime
+		}
+	}
+
+	for _, span := range spans {
+		parent := span.ParentID
+		if !known[parent] {
+			// orphans are shown at the top level rather than dropped
+			parent = 0
+		}
+
+		children[parent] = append(children[parent], span)
+	}
+
+	res.Start = start.UTC()
+	res.Duration = end.Sub(start)
+
+	var walk func(parent trace.SpanID, depth int)
+
+	walk = func(parent trace.SpanID, depth int) {
+		siblings := children[parent]
+
+		sort.SliceStable(siblings, func(i, j int) bool {
+			return siblings[i].StartTime.Before(siblings[j].StartTime)
+		})
+
+		for _, span := range siblings {
+			row := newRow(span, depth, start, res.Duration)
+			if row.Error {
+				res.Error = true
+			}
+
+			res.Rows = append(res.Rows, row)
+
+			walk(span.ID, depth+1)
+		}
+	}
+
+	walk(0, 0)
+
+	return res
+}
+
+func newRow(span *trace.Span, depth int, start time.Time, total time.Duration) Row {
+	row := Row{
+		ID:        span.ID.String(),
+		Depth:     depth,
+		Service:   span.Service,
+		Operation: span.OperationName,
+		Offset:    span.StartTime.Sub(start),
+		Duration:  span.Duration(),
+		Error:     span.IsError(),
+	}
+
+	if total > 0 {
+		row.Left = 100 * float64(row.Offset) / float64(total)
+		row.Width = 100 * float64(row.Duration) / float64(total)
+	}
+
+	for _, tag := range span.Tags {
+		value := tag.ScalarValue()
+
+		if tag.Key == "incomplete" {
+			row.Incomplete, _ = value.(bool)
+		}
+
+		row.Tags = append(row.Tags, Tag{
+			Key:   tag.Key,
+			Value: fmt.Sprintf("%v", value),
+		})
+	}
+
+	if row.Error && span.Status.Message != "" {
+		row.Tags = append(row.Tags, Tag{
+			Key:   "error",
+			Value: span.Status.Message,
+		})
+	}
+
+	for _, event := range span.Events {
+		l := Log{
+			Offset: event.Time.Sub(start),
+			Event:  event.Name,
+		}
+
+		for _, field := range event.Fields {
+			value := field.ScalarValue()
+
+			switch field.Key {
+			case "source":
+				l.Source = fmt.Sprintf("%v", value)
+			case "line":
+				l.Line, _ = value.(int64)
+			case "line.end":
+				l.EndLine, _ = value.(int64)
+			default:
+				if l.Message != "" {
+					l.Message += "\n"
+				}
+
+				if field.Key == "message" {
+					l.Message += fmt.Sprintf("%v", value)
+				} else {
+					l.Message += fmt.Sprintf("%s: %v", field.Key, value)
+				}
+			}
+		}
+
+		row.Logs = append(row.Logs, l)
+	}
+
+	return row
+}
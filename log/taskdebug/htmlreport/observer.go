@@ -0,0 +1,23 @@
+package htmlreport
+
+import (
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug/timeline"
+	"github.com/dpb587/bosh-log-tracer/observer/context"
+)
+
+type ObserverOptions struct {
+	Timeline timeline.ObserverOptions
+
+	// Report receives the trace; it may be shared between observers to
+	// combine several tasks.
+	Report *Report
+
+	// Name labels the trace; defaults to the task.
+	Name string
+}
+
+// NewObserver adds the timeline of a task to a report once it has been
+// committed.
+func NewObserver(ctx *context.Context, o ObserverOptions) *timeline.Collector {
+	return timeline.NewCollector(ctx, o.Timeline, o.Report, o.Name)
+}
@@ -0,0 +1,130 @@
+package htmlreport
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dpb587/bosh-log-tracer/log/input"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug/parser"
+	"github.com/dpb587/bosh-log-tracer/observer/context"
+	"github.com/dpb587/bosh-log-tracer/pipeline"
+	"github.com/dpb587/bosh-log-tracer/trace"
+)
+
+func TestObserverReport(t *testing.T) {
+	report := &Report{}
+
+	for _, name := range []string{"", "retry"} {
+		obs := NewObserver(&context.Context{}, ObserverOptions{
+			Report: report,
+			Name:   name,
+		})
+
+		err := pipeline.Run(input.NewFileInput(filepath.Join("..", "testdata", "deploy-failed.log")), parser.NewParser(parser.Options{}), obs)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	if len(report.Traces) != 2 {
+		t.Fatalf("expected 2 traces, but got %d", len(report.Traces))
+	}
+
+	if name := report.Traces[0].Name; !strings.HasPrefix(name, "task ") {
+		t.Errorf("expected trace to be named after the task, but got %q", name)
+	} else if name := report.Traces[1].Name; name != "retry" {
+		t.Errorf("expected trace to be named retry, but got %q", name)
+	}
+
+	tr := report.Traces[0]
+
+	if !tr.Error {
+		t.Errorf("expected the failed task to be an error")
+	} else if len(tr.Rows) == 0 {
+		t.Fatalf("expected rows")
+	} else if tr.Rows[0].Depth != 0 || tr.Rows[0].Left != 0 || tr.Rows[0].Width != 100 {
+		t.Errorf("expected the task to span the timeline, but got %+v", tr.Rows[0])
+	}
+
+	for idx, row := range tr.Rows[1:] {
+		if row.Depth < 1 || row.Depth > tr.Rows[idx].Depth+1 {
+			t.Errorf("%s: expected to follow its parent, but got depth %d after %d", row.Operation, row.Depth, tr.Rows[idx].Depth)
+		}
+
+		if row.Left < 0 || row.Width < 0 || row.Left+row.Width > 100.0001 {
+			t.Errorf("%s: expected to be placed within the timeline, but got %f+%f", row.Operation, row.Left, row.Width)
+		}
+	}
+
+	buf := &bytes.Buffer{}
+
+	err := report.Write(buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for _, expected := range []string{"<html", tr.Name, "retry", tr.Rows[len(tr.Rows)-1].Operation} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("expected report to contain %q", expected)
+		}
+	}
+}
+
+func TestNewTrace(t *testing.T) {
+	t0 := time.Date(2018, 4, 21, 14, 3, 26, 0, time.UTC)
+
+	tr := trace.NewTrace(nil)
+
+	task := tr.StartSpan("worker", "task", nil, t0)
+	second := tr.StartSpan("stage", "second", task, t0.Add(2*time.Second))
+	first := tr.StartSpan("stage", "first", task, t0.Add(time.Second))
+	child := tr.StartSpan("cpi", "create_vm <script>", first, t0.Add(time.Second))
+	orphan := tr.StartSpan("lock", "orphan", nil, t0.Add(3*time.Second))
+	orphan.ParentID = trace.SpanID(12345)
+
+	child.SetError(t0.Add(1500*time.Millisecond), "Bosh::Clouds::VMCreationFailed", "failed", trace.Tag{Key: "line", Value: 42})
+	child.SetTag("incomplete", true)
+
+	for _, sp := range []*trace.Span{child, first, second, orphan} {
+		sp.Finish(sp.StartTime.Add(time.Second))
+	}
+
+	task.Finish(t0.Add(4 * time.Second))
+
+	actual := NewTrace("test", tr)
+
+	if actual.Duration != 4*time.Second || !actual.Start.Equal(t0) || !actual.Error {
+		t.Errorf("unexpected trace: %+v", actual)
+	}
+
+	var rows []string
+
+	for _, row := range actual.Rows {
+		rows = append(rows, strings.Repeat("  ", row.Depth)+row.Operation)
+	}
+
+	expected := "task\n  first\n    create_vm <script>\n  second\norphan"
+	if strings.Join(rows, "\n") != expected {
+		t.Fatalf("expected rows:\n%s\nbut got:\n%s", expected, strings.Join(rows, "\n"))
+	}
+
+	row := actual.Rows[2]
+
+	if !row.Error || !row.Incomplete || row.Left != 25 || row.Width != 25 {
+		t.Errorf("unexpected row: %+v", row)
+	} else if len(row.Logs) != 1 || row.Logs[0].Line != 42 || row.Logs[0].Offset != 1500*time.Millisecond || row.Logs[0].Message != "error.kind: Bosh::Clouds::VMCreationFailed\nfailed" {
+		t.Errorf("unexpected logs: %+v", row.Logs)
+	}
+
+	buf := &bytes.Buffer{}
+
+	err := (&Report{Traces: []Trace{actual}}).Write(buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if strings.Contains(buf.String(), "<script>") {
+		t.Errorf("expected operations to be escaped")
+	}
+}
@@ -0,0 +1,97 @@
+package htmlreport
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"time"
+)
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"duration": formatDuration,
+	"percent": func(v float64) string {
+		return fmt.Sprintf("%.4f%%", v)
+	},
+	"indent": func(depth int) string {
+		return fmt.Sprintf("%dpx", 8+depth*14)
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{ range $i, $t := .Traces }}{{ if $i }}, {{ end }}{{ $t.Name }}{{ end }}</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; font-size: 13px; margin: 0; color: #222; }
+h1 { font-size: 16px; margin: 0; padding: 12px 8px; background: #f4f4f4; border-bottom: 1px solid #ddd; }
+h1 small { color: #666; font-weight: normal; margin-left: 8px; }
+h1.error { background: #fbe9e9; }
+details { border-bottom: 1px solid #f0f0f0; }
+details[open] { background: #fafafa; }
+summary { display: flex; align-items: center; cursor: pointer; list-style: none; height: 22px; }
+summary::-webkit-details-marker { display: none; }
+summary:hover { background: #f0f6ff; }
+.label { flex: 0 0 34%; overflow: hidden; white-space: nowrap; text-overflow: ellipsis; box-sizing: border-box; }
+.label .service { color: #888; margin-right: 6px; }
+.timeline { flex: 1; position: relative; height: 100%; margin-right: 8px; }
+.bar { position: absolute; top: 5px; height: 12px; min-width: 2px; border-radius: 2px; background: #7a9cc6; }
+.bar.error { background: #d9534f; }
+.bar.incomplete { background-image: repeating-linear-gradient(45deg, transparent, transparent 4px, rgba(255,255,255,.5) 4px, rgba(255,255,255,.5) 8px); }
+.bar span { position: absolute; left: 100%; margin-left: 4px; top: -2px; font-size: 11px; color: #666; white-space: nowrap; }
+.bar.service-worker { background: #5b5b5b; }
+.bar.service-stage { background: #8e6bbf; }
+.bar.service-updater, .bar.service-creator { background: #4c9f70; }
+.bar.service-compiler { background: #d0913e; }
+.bar.service-nats { background: #3f8fc5; }
+.bar.service-cpi, .bar.service-aws { background: #c5723f; }
+.bar.service-lock { background: #aaaaaa; }
+.bar.service-db { background: #b5b5d6; }
+.detail { padding: 6px 8px 10px 34%; }
+.detail table { border-collapse: collapse; margin-bottom: 6px; }
+.detail td { padding: 1px 8px 1px 0; vertical-align: top; }
+.detail td.key { color: #666; }
+.detail pre { margin: 0; white-space: pre-wrap; word-break: break-all; font-size: 11px; }
+</style>
+</head>
+<body>
+{{ range $traceIdx, $trace := .Traces }}
+<section>
+<h1{{ if $trace.Error }} class="error"{{ end }}>{{ $trace.Name }}<small>{{ $trace.Start.Format "2006-01-02 15:04:05.000000 UTC" }}; {{ duration $trace.Duration }}</small></h1>
+{{ range $trace.Rows }}
+<details id="t{{ $traceIdx }}-{{ .ID }}">
+<summary>
+<div class="label" style="padding-left: {{ indent .Depth }}"><span class="service">{{ .Service }}</span>{{ .Operation }}</div>
+<div class="timeline"><div class="bar service-{{ .Service }}{{ if .Error }} error{{ end }}{{ if .Incomplete }} incomplete{{ end }}" style="left: {{ percent .Left }}; width: {{ percent .Width }}"><span>{{ duration .Duration }}</span></div></div>
+</summary>
+<div class="detail">
+<table>
+<tr><td class="key">start</td><td>+{{ duration .Offset }}</td></tr>
+<tr><td class="key">duration</td><td>{{ duration .Duration }}</td></tr>
+{{ range .Tags }}<tr><td class="key">{{ .Key }}</td><td>{{ .Value }}</td></tr>
+{{ end }}</table>
+{{ if .Logs }}<table>
+{{ range .Logs }}<tr><td class="key">+{{ duration .Offset }}</td><td class="key">{{ .Event }}</td><td class="key">{{ if .Line }}{{ if .Source }}{{ .Source }}:{{ end }}{{ .Line }}{{ if .EndLine }}-{{ .EndLine }}{{ end }}{{ end }}</td><td><pre>{{ .Message }}</pre></td></tr>
+{{ end }}</table>{{ end }}
+</div>
+</details>
+{{ end }}
+</section>
+{{ end }}
+</body>
+</html>
+`))
+
+// Write renders the report as a single, self-contained HTML page.
+func (r *Report) Write(w io.Writer) error {
+	return reportTemplate.Execute(w, r)
+}
+
+func formatDuration(d time.Duration) string {
+	switch {
+	case d >= time.Minute:
+		return d.Round(time.Second).String()
+	case d >= time.Second:
+		return fmt.Sprintf("%.2fs", d.Seconds())
+	}
+
+	return fmt.Sprintf("%.1fms", float64(d)/float64(time.Millisecond))
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/pkg/errors"
 )
 
 type InstanceAspectChangedMessage struct {
@@ -28,15 +29,15 @@ type packageChangeSet map[string]struct {
 	BlobstoreID string `json:"blobstore_id"`
 }
 
-func (m InstanceAspectChangedMessage) GetChangedFromTags() map[string]interface{} {
+func (m InstanceAspectChangedMessage) GetChangedFromTags() (map[string]interface{}, error) {
 	return m.getChangedTags(m.ChangedFrom)
 }
 
-func (m InstanceAspectChangedMessage) GetChangedToTags() map[string]interface{} {
+func (m InstanceAspectChangedMessage) GetChangedToTags() (map[string]interface{}, error) {
 	return m.getChangedTags(m.ChangedTo)
 }
 
-func (m InstanceAspectChangedMessage) getChangedTags(data string) map[string]interface{} {
+func (m InstanceAspectChangedMessage) getChangedTags(data string) (map[string]interface{}, error) {
 	res := map[string]interface{}{}
 
 	switch m.Aspect {
@@ -50,7 +51,7 @@ func (m InstanceAspectChangedMessage) getChangedTags(data string) map[string]int
 
 		err := json.Unmarshal([]byte(data), &v)
 		if err != nil {
-			panic(err)
+			return nil, errors.Wrapf(err, "unmarshaling %s change", m.Aspect)
 		}
 
 		for _, pkg := range v {
@@ -60,7 +61,7 @@ func (m InstanceAspectChangedMessage) getChangedTags(data string) map[string]int
 		}
 	}
 
-	return res
+	return res, nil
 }
 
 func (m InstanceAspectChangedMessage) GetChangedPackages() []string {
@@ -0,0 +1,228 @@
+package jaeger
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/dpb587/bosh-log-tracer/trace"
+	opentracing "github.com/opentracing/opentracing-go"
+	opentracingext "github.com/opentracing/opentracing-go/ext"
+	opentracinglog "github.com/opentracing/opentracing-go/log"
+	jaeger "github.com/uber/jaeger-client-go"
+	"github.com/uber/jaeger-client-go/transport"
+)
+
+// Endpoint is where spans are sent; either a jaeger agent (UDP) or collector
+// (HTTP). The local agent is used by default.
+type Endpoint struct {
+	// AgentHostPort is the host:port of an agent (e.g. localhost:6831).
+	AgentHostPort string
+
+	// CollectorEndpoint is the URL of a collector's thrift endpoint (e.g.
+	// http://jaeger-collector:14268/api/traces); it takes precedence over the
+	// agent.
+	CollectorEndpoint string
+
+	// Headers are added to collector requests (e.g. Authorization).
+	Headers map[string]string
+}
+
+func (e Endpoint) NewReporter() (jaeger.Reporter, error) {
+	var sender jaeger.Transport
+
+	if e.CollectorEndpoint != "" {
+		var opts []transport.HTTPOption
+
+		if len(e.Headers) > 0 {
+			opts = append(opts, transport.HTTPRoundTripper(headerRoundTripper{
+				headers: e.Headers,
+				next:    http.DefaultTransport,
+			}))
+		}
+
+		sender = transport.NewHTTPTransport(e.CollectorEndpoint, opts...)
+	} else {
+		var err error
+
+		sender, err = jaeger.NewUDPTransport(e.AgentHostPort, 0)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return jaeger.NewRemoteReporter(
+		sender,
+		jaeger.ReporterOptions.Logger(jaeger.StdLogger),
+	), nil
+}
+
+type headerRoundTripper struct {
+	headers map[string]string
+	next    http.RoundTripper
+}
+
+func (rt headerRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	// round trippers should not modify the original request
+	clone := *req
+	clone.Header = http.Header{}
+
+	for k, v := range req.Header {
+		clone.Header[k] = v
+	}
+
+	for k, v := range rt.headers {
+		clone.Header.Set(k, v)
+	}
+
+	return rt.next.RoundTrip(&clone)
+}
+
+// Exporter replays the spans of a trace through jaeger tracers, one for each
+// service, keeping the IDs and times of the original spans.
+type Exporter struct {
+	Endpoint Endpoint
+
+	// NewReporter creates the reporter of a service's tracer; defaults to one
+	// for the endpoint.
+	NewReporter func() (jaeger.Reporter, error)
+}
+
+type exportTracer struct {
+	t opentracing.Tracer
+	c io.Closer
+}
+
+func (e Exporter) Export(tr *trace.Trace) error {
+	return e.ExportSpans(tr.FinishedSpans()...)
+}
+
+// ExportSpans replays individual spans; their parents may have been exported
+// separately (or not yet at all), since they are referenced by ID.
+func (e Exporter) ExportSpans(spans ...*trace.Span) error {
+	// tracers generate IDs through this while replaying each span
+	var nextID trace.SpanID
+	var nextTraceIDHigh uint64
+
+	tracers := map[string]exportTracer{}
+
+	getTracer := func(service string) (opentracing.Tracer, error) {
+		if t, found := tracers[service]; found {
+			return t.t, nil
+		}
+
+		reporter, err := e.newReporter()
+		if err != nil {
+			return nil, fmt.Errorf("creating %s reporter: %s", service, err)
+		}
+
+		t, c := jaeger.NewTracer(
+			service,
+			jaeger.NewConstSampler(true),
+			reporter,
+			jaeger.TracerOptions.RandomNumber(func() uint64 { return uint64(nextID) }),
+			jaeger.TracerOptions.Gen128Bit(true),
+			jaeger.TracerOptions.HighTraceIDGenerator(func() uint64 { return nextTraceIDHigh }),
+		)
+
+		tracers[service] = exportTracer{t: t, c: c}
+
+		return t, nil
+	}
+
+	for _, span := range spans {
+		t, err := getTracer(span.Service)
+		if err != nil {
+			return err
+		}
+
+		opts := []opentracing.StartSpanOption{
+			opentracing.StartTime(span.StartTime),
+		}
+
+		parentID := span.ParentID
+		if parentID == 0 && span.ID != trace.SpanID(span.TraceID.Low) {
+			// tracers start a new trace for spans without a parent, so other
+			// spans without one (e.g. the partial copy of the root) are kept in
+			// the trace by referencing its root
+			parentID = trace.SpanID(span.TraceID.Low)
+		}
+
+		if parentID != 0 {
+			parent := jaeger.NewSpanContext(
+				jaeger.TraceID{High: span.TraceID.High, Low: span.TraceID.Low},
+				jaeger.SpanID(parentID),
+				0,
+				true,
+				nil,
+			)
+
+			opts = append(opts, opentracing.ChildOf(parent))
+		}
+
+		nextID = span.ID
+		nextTraceIDHigh = span.TraceID.High
+
+		sp := t.StartSpan(span.OperationName, opts...)
+
+		for _, tag := range span.Tags {
+			sp.SetTag(tag.Key, tag.ScalarValue())
+		}
+
+		if span.IsError() {
+			opentracingext.Error.Set(sp, true)
+		}
+
+		var logs []opentracing.LogRecord
+
+		for _, event := range span.Events {
+			logs = append(logs, opentracing.LogRecord{
+				Timestamp: event.Time,
+				Fields:    newLogFields(event),
+			})
+		}
+
+		sp.FinishWithOptions(opentracing.FinishOptions{
+			FinishTime: span.FinishTime,
+			LogRecords: logs,
+		})
+	}
+
+	for service, t := range tracers {
+		err := t.c.Close()
+		if err != nil {
+			return fmt.Errorf("closing %s tracer: %s", service, err)
+		}
+	}
+
+	return nil
+}
+
+func (e Exporter) newReporter() (jaeger.Reporter, error) {
+	if e.NewReporter != nil {
+		return e.NewReporter()
+	}
+
+	return e.Endpoint.NewReporter()
+}
+
+func newLogFields(event trace.Event) []opentracinglog.Field {
+	fields := []opentracinglog.Field{
+		opentracinglog.String("event", event.Name),
+	}
+
+	for _, field := range event.Fields {
+		switch v := field.ScalarValue().(type) {
+		case bool:
+			fields = append(fields, opentracinglog.Bool(field.Key, v))
+		case int64:
+			fields = append(fields, opentracinglog.Int64(field.Key, v))
+		case float64:
+			fields = append(fields, opentracinglog.Float64(field.Key, v))
+		default:
+			fields = append(fields, opentracinglog.String(field.Key, v.(string)))
+		}
+	}
+
+	return fields
+}
@@ -0,0 +1,143 @@
+package jaeger
+
+import (
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/input"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug/parser"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug/timeline"
+	"github.com/dpb587/bosh-log-tracer/observer"
+	"github.com/dpb587/bosh-log-tracer/observer/context"
+	"github.com/dpb587/bosh-log-tracer/pipeline"
+	"github.com/dpb587/bosh-log-tracer/trace"
+	jaeger "github.com/uber/jaeger-client-go"
+	jaegerthrift "github.com/uber/jaeger-client-go/thrift-gen/jaeger"
+)
+
+func newRecordingExporter() (Exporter, *jaeger.InMemoryReporter) {
+	reporter := jaeger.NewInMemoryReporter()
+
+	return Exporter{
+		NewReporter: func() (jaeger.Reporter, error) {
+			return reporter, nil
+		},
+	}, reporter
+}
+
+func getThriftTag(sp *jaegerthrift.Span, key string) (*jaegerthrift.Tag, bool) {
+	for _, tag := range sp.Tags {
+		if tag.Key == key {
+			return tag, true
+		}
+	}
+
+	return nil, false
+}
+
+// flushingObserver flushes after every line, as if following a running task.
+// It flushes twice, where the second flush has nothing new to send.
+type flushingObserver struct {
+	observer.Observer
+}
+
+func (o flushingObserver) Handle(l log.Line) error {
+	err := o.Observer.Handle(l)
+	if err != nil {
+		return err
+	}
+
+	err = o.Observer.Flush()
+	if err != nil {
+		return err
+	}
+
+	return o.Observer.Flush()
+}
+
+func TestObserverFlush(t *testing.T) {
+	for _, deterministic := range []bool{false, true} {
+		exporter, reporter := newRecordingExporter()
+
+		obs := NewObserver(&context.Context{}, ObserverOptions{
+			Timeline: timeline.ObserverOptions{
+				DeterministicIDs: deterministic,
+			},
+		})
+		obs.exporter = exporter
+
+		err := pipeline.Run(input.NewFileInput(filepath.Join("..", "testdata", "deploy-failed.log")), parser.NewParser(parser.Options{}), flushingObserver{obs})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		tr := obs.observer.Trace()
+		sent := map[int64]int{}
+		partialEnds := map[int64][]int64{}
+		partialOf := map[int64]int64{}
+
+		for _, s := range reporter.GetSpans() {
+			sp := jaeger.BuildJaegerThrift(s.(*jaeger.Span))
+
+			if traceID := (trace.TraceID{High: uint64(sp.TraceIdHigh), Low: uint64(sp.TraceIdLow)}); traceID != tr.ID {
+				t.Errorf("deterministic %t: expected %s (%x) in trace %s, but got %s", deterministic, sp.OperationName, uint64(sp.SpanId), tr.ID, traceID)
+			}
+
+			if tag, partial := getThriftTag(sp, "partial.span_id"); partial {
+				id, err := strconv.ParseUint(*tag.VStr, 16, 64)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				partialOf[sp.SpanId] = int64(id)
+				partialEnds[sp.SpanId] = append(partialEnds[sp.SpanId], sp.StartTime+sp.Duration)
+
+				continue
+			}
+
+			sent[sp.SpanId]++
+		}
+
+		if len(partialEnds) == 0 {
+			t.Errorf("deterministic %t: expected partial spans", deterministic)
+		}
+
+		for id, count := range sent {
+			if count > 1 {
+				t.Errorf("deterministic %t: expected span %x to be sent once, but it was sent %d times", deterministic, uint64(id), count)
+			}
+		}
+
+		for id, ends := range partialEnds {
+			for idx := 1; idx < len(ends); idx++ {
+				if ends[idx] <= ends[idx-1] {
+					t.Errorf("deterministic %t: expected partial span %x to only be sent again once it progressed, but got %v", deterministic, uint64(id), ends)
+				}
+			}
+		}
+
+		var rootPartials int
+
+		for id, of := range partialOf {
+			if trace.SpanID(of) == trace.SpanID(tr.ID.Low) {
+				rootPartials = len(partialEnds[id])
+			}
+		}
+
+		if rootPartials < 2 {
+			t.Errorf("deterministic %t: expected the partial root to be sent as the task progressed, but it was sent %d times", deterministic, rootPartials)
+		}
+
+		for _, sp := range tr.FinishedSpans() {
+			if sent[int64(sp.ID)] != 1 {
+				t.Errorf("deterministic %t: expected %s (%s) to be sent", deterministic, sp.OperationName, sp.ID)
+			}
+		}
+
+		if len(sent) != len(tr.FinishedSpans()) {
+			t.Errorf("deterministic %t: expected %d spans, but got %d", deterministic, len(tr.FinishedSpans()), len(sent))
+		}
+	}
+}
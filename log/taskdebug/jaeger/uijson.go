@@ -0,0 +1,180 @@
+package jaeger
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/dpb587/bosh-log-tracer/trace"
+)
+
+// The following types mirror the JSON which the Jaeger UI loads through its
+// "Upload JSON" (the same structure returned by its /api/traces endpoint).
+
+type UIDocument struct {
+	Data []UITrace `json:"data"`
+}
+
+type UITrace struct {
+	TraceID   string               `json:"traceID"`
+	Spans     []UISpan             `json:"spans"`
+	Processes map[string]UIProcess `json:"processes"`
+	Warnings  []string             `json:"warnings"`
+}
+
+type UISpan struct {
+	TraceID       string        `json:"traceID"`
+	SpanID        string        `json:"spanID"`
+	Flags         int32         `json:"flags"`
+	OperationName string        `json:"operationName"`
+	References    []UIReference `json:"references"`
+	StartTime     int64         `json:"startTime"`
+	Duration      int64         `json:"duration"`
+	Tags          []UIKeyValue  `json:"tags"`
+	Logs          []UILog       `json:"logs"`
+	ProcessID     string        `json:"processID"`
+	Warnings      []string      `json:"warnings"`
+}
+
+type UIReference struct {
+	RefType string `json:"refType"`
+	TraceID string `json:"traceID"`
+	SpanID  string `json:"spanID"`
+}
+
+type UILog struct {
+	Timestamp int64        `json:"timestamp"`
+	Fields    []UIKeyValue `json:"fields"`
+}
+
+type UIProcess struct {
+	ServiceName string       `json:"serviceName"`
+	Tags        []UIKeyValue `json:"tags"`
+}
+
+type UIKeyValue struct {
+	Key   string      `json:"key"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+// NewUIDocument converts the finished spans of traces. Spans are associated
+// with a process for each service.
+func NewUIDocument(traces ...*trace.Trace) UIDocument {
+	doc := UIDocument{
+		Data: []UITrace{},
+	}
+
+	for _, tr := range traces {
+		spans := tr.FinishedSpans()
+		if len(spans) == 0 {
+			continue
+		}
+
+		uiTrace := UITrace{
+			TraceID:   tr.ID.String(),
+			Spans:     []UISpan{},
+			Processes: map[string]UIProcess{},
+		}
+
+		processIDs := map[string]string{}
+
+		for _, span := range spans {
+			processID, found := processIDs[span.Service]
+			if !found {
+				processID = fmt.Sprintf("p%d", len(processIDs)+1)
+				processIDs[span.Service] = processID
+
+				uiTrace.Processes[processID] = UIProcess{
+					ServiceName: span.Service,
+					Tags:        []UIKeyValue{},
+				}
+			}
+
+			uiTrace.Spans = append(uiTrace.Spans, newUISpan(processID, span))
+		}
+
+		doc.Data = append(doc.Data, uiTrace)
+	}
+
+	return doc
+}
+
+// WriteUIJSON writes traces in the format accepted by the Jaeger UI.
+func WriteUIJSON(w io.Writer, traces ...*trace.Trace) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+
+	return encoder.Encode(NewUIDocument(traces...))
+}
+
+func newUISpan(processID string, in *trace.Span) UISpan {
+	out := UISpan{
+		TraceID:       in.TraceID.String(),
+		SpanID:        in.ID.String(),
+		Flags:         1, // sampled
+		OperationName: in.OperationName,
+		References:    []UIReference{},
+		StartTime:     toMicroseconds(in.StartTime),
+		Duration:      toMicroseconds(in.FinishTime) - toMicroseconds(in.StartTime),
+		Tags:          newUIKeyValues(in.Tags),
+		Logs:          []UILog{},
+		ProcessID:     processID,
+	}
+
+	if in.ParentID != 0 {
+		out.References = append(out.References, UIReference{
+			RefType: "CHILD_OF",
+			TraceID: in.TraceID.String(),
+			SpanID:  in.ParentID.String(),
+		})
+	}
+
+	if in.IsError() {
+		out.Tags = append(out.Tags, UIKeyValue{Key: "error", Type: "bool", Value: true})
+	}
+
+	for _, event := range in.Events {
+		out.Logs = append(out.Logs, UILog{
+			Timestamp: toMicroseconds(event.Time),
+			Fields: append(
+				[]UIKeyValue{{Key: "event", Type: "string", Value: event.Name}},
+				newUIKeyValues(event.Fields)...,
+			),
+		})
+	}
+
+	return out
+}
+
+func newUIKeyValues(tags []trace.Tag) []UIKeyValue {
+	res := []UIKeyValue{}
+
+	for _, tag := range tags {
+		kv := UIKeyValue{
+			Key:   tag.Key,
+			Value: tag.ScalarValue(),
+		}
+
+		switch kv.Value.(type) {
+		case float64:
+			kv.Type = "float64"
+		case bool:
+			kv.Type = "bool"
+		case int64:
+			kv.Type = "int64"
+		default:
+			kv.Type = "string"
+		}
+
+		res = append(res, kv)
+	}
+
+	return res
+}
+
+func toMicroseconds(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Microsecond)
+}
@@ -0,0 +1,158 @@
+package jaeger
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/dpb587/bosh-log-tracer/trace"
+)
+
+var update = flag.Bool("update", false, "update the golden files of testdata")
+
+var hexID = regexp.MustCompile(`^([0-9a-f]{16}|[0-9a-f]{32})$`)
+
+func newUITestTrace() *trace.Trace {
+	t0 := time.Date(2018, 4, 21, 14, 3, 26, 123456789, time.UTC)
+
+	tr := trace.NewTrace(nil)
+
+	task := tr.StartSpan("director", "task", nil, t0, trace.Tag{Key: "task.id", Value: 1234})
+	stage := tr.StartSpan("director", "stage: Updating instance", task, t0.Add(time.Second), trace.Tag{Key: "stage.total", Value: 1})
+	cpi := tr.StartSpan("cpi", "create_vm", stage, t0.Add(1500*time.Millisecond))
+	cpi.SetTag("cpi.duration", 1.5)
+	cpi.SetTag("cpi.retry", false)
+	cpi.SetError(t0.Add(3*time.Second), "Bosh::Clouds::VMCreationFailed", "VM failed to create", trace.Tag{Key: "ok_to_retry", Value: true})
+	cpi.Finish(t0.Add(3 * time.Second))
+	stage.Finish(t0.Add(4 * time.Second))
+	task.Finish(t0.Add(5*time.Second + 999*time.Nanosecond))
+
+	// unfinished spans are not exported
+	tr.StartSpan("director", "dangling", task, t0)
+
+	tr.DeriveIDs("uijson")
+
+	return tr
+}
+
+func TestWriteUIJSONGolden(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	err := WriteUIJSON(buf, newUITestTrace())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	actual := buf.Bytes()
+	goldenPath := filepath.Join("testdata", "uijson.golden")
+
+	if *update {
+		err := ioutil.WriteFile(goldenPath, actual, 0644)
+		if err != nil {
+			t.Fatalf("writing golden file: %s", err)
+		}
+	}
+
+	expected, err := ioutil.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("reading golden file (use -update to create it): %s", err)
+	}
+
+	if !bytes.Equal(actual, expected) {
+		t.Errorf("document does not match %s (use -update to accept it)\nexpected:\n%s\nactual:\n%s", goldenPath, expected, actual)
+	}
+}
+
+func TestWriteUIJSONSchema(t *testing.T) {
+	tr := newUITestTrace()
+	buf := &bytes.Buffer{}
+
+	err := WriteUIJSON(buf, tr)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var doc UIDocument
+
+	decoder := json.NewDecoder(buf)
+	decoder.DisallowUnknownFields()
+
+	err = decoder.Decode(&doc)
+	if err != nil {
+		t.Fatalf("decoding document: %s", err)
+	}
+
+	if len(doc.Data) != 1 {
+		t.Fatalf("expected 1 trace, but got %d", len(doc.Data))
+	}
+
+	uiTrace := doc.Data[0]
+
+	if !hexID.MatchString(uiTrace.TraceID) {
+		t.Errorf("expected hex trace ID, but got %q", uiTrace.TraceID)
+	}
+
+	expected := tr.FinishedSpans()
+
+	if len(uiTrace.Spans) != len(expected) {
+		t.Fatalf("expected %d spans, but got %d", len(expected), len(uiTrace.Spans))
+	}
+
+	spanIDs := map[string]bool{}
+
+	for _, span := range uiTrace.Spans {
+		spanIDs[span.SpanID] = true
+	}
+
+	for i, span := range uiTrace.Spans {
+		sp := expected[i]
+
+		if span.TraceID != uiTrace.TraceID {
+			t.Errorf("%s: expected trace ID %s, but got %s", span.OperationName, uiTrace.TraceID, span.TraceID)
+		} else if !hexID.MatchString(span.SpanID) || span.SpanID != sp.ID.String() {
+			t.Errorf("%s: expected hex span ID %s, but got %q", span.OperationName, sp.ID, span.SpanID)
+		}
+
+		if sp.ParentID == 0 {
+			if len(span.References) != 0 {
+				t.Errorf("%s: expected no references, but got %v", span.OperationName, span.References)
+			}
+		} else if len(span.References) != 1 {
+			t.Errorf("%s: expected 1 reference, but got %v", span.OperationName, span.References)
+		} else if ref := span.References[0]; ref.RefType != "CHILD_OF" || ref.TraceID != uiTrace.TraceID || ref.SpanID != sp.ParentID.String() || !spanIDs[ref.SpanID] {
+			t.Errorf("%s: unexpected reference %v", span.OperationName, ref)
+		}
+
+		process, found := uiTrace.Processes[span.ProcessID]
+		if !found {
+			t.Errorf("%s: process %q is missing", span.OperationName, span.ProcessID)
+		} else if process.ServiceName != sp.Service {
+			t.Errorf("%s: expected process of %s, but got %s", span.OperationName, sp.Service, process.ServiceName)
+		}
+
+		if span.StartTime != sp.StartTime.UnixNano()/1000 {
+			t.Errorf("%s: expected start of %dus, but got %d", span.OperationName, sp.StartTime.UnixNano()/1000, span.StartTime)
+		} else if span.StartTime+span.Duration != sp.FinishTime.UnixNano()/1000 {
+			t.Errorf("%s: expected finish of %dus, but got %d", span.OperationName, sp.FinishTime.UnixNano()/1000, span.StartTime+span.Duration)
+		}
+
+		if len(span.Logs) != len(sp.Events) {
+			t.Errorf("%s: expected %d logs, but got %d", span.OperationName, len(sp.Events), len(span.Logs))
+		}
+
+		for j, log := range span.Logs {
+			if log.Timestamp != sp.Events[j].Time.UnixNano()/1000 {
+				t.Errorf("%s: expected log at %dus, but got %d", span.OperationName, sp.Events[j].Time.UnixNano()/1000, log.Timestamp)
+			}
+		}
+	}
+
+	if len(uiTrace.Processes) != 2 {
+		t.Errorf("expected a process for each of 2 services, but got %d", len(uiTrace.Processes))
+	}
+}
@@ -2,9 +2,9 @@ package taskdebug
 
 import (
 	"encoding/json"
-	"fmt"
 
 	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/pkg/errors"
 )
 
 type NATSMessageMessage struct {
@@ -17,7 +17,7 @@ type NATSMessageMessage struct {
 
 var _ log.Line = &NATSMessageMessage{}
 
-func (m NATSMessageMessage) GetReceivedTaskID() string {
+func (m NATSMessageMessage) GetReceivedTaskID() (string, error) {
 	var payload struct {
 		Value struct {
 			AgentTaskID string `json:"agent_task_id"`
@@ -26,14 +26,34 @@ func (m NATSMessageMessage) GetReceivedTaskID() string {
 
 	err := json.Unmarshal([]byte(m.Payload), &payload)
 	if err != nil {
-		fmt.Println(m.Payload)
-		panic(err)
+		return "", errors.Wrap(err, "unmarshaling received task payload")
 	}
 
-	return payload.Value.AgentTaskID
+	return payload.Value.AgentTaskID, nil
 }
 
-func (m NATSMessageMessage) GetReceivedState() string {
+// GetReceivedException returns the message of an agent exception, or an empty
+// string if the response was not an exception.
+func (m NATSMessageMessage) GetReceivedException() (string, error) {
+	var payload struct {
+		Exception *struct {
+			Message string `json:"message"`
+		} `json:"exception"`
+	}
+
+	err := json.Unmarshal([]byte(m.Payload), &payload)
+	if err != nil {
+		return "", errors.Wrap(err, "unmarshaling received exception payload")
+	} else if payload.Exception == nil {
+		return "", nil
+	} else if payload.Exception.Message == "" {
+		return "unknown agent exception", nil
+	}
+
+	return payload.Exception.Message, nil
+}
+
+func (m NATSMessageMessage) GetReceivedState() (string, error) {
 	var payload struct {
 		Value struct {
 			State string `json:"state"`
@@ -48,26 +68,25 @@ func (m NATSMessageMessage) GetReceivedState() string {
 
 		err := json.Unmarshal([]byte(m.Payload), &payload1)
 		if err != nil {
-			panic(err)
+			return "", errors.Wrap(err, "unmarshaling received state payload")
 		}
 
 		// if it unserialized, assume it finished its task and this was the value
-		return "done"
+		return "done", nil
 	}
 
-	return payload.Value.State
+	return payload.Value.State, nil
 }
 
-func (m NATSMessageMessage) GetReceivedDrainValue() int64 {
+func (m NATSMessageMessage) GetReceivedDrainValue() (int64, error) {
 	var payload struct {
 		Value int64 `json:"value"`
 	}
 
 	err := json.Unmarshal([]byte(m.Payload), &payload)
 	if err != nil {
-		fmt.Println(m.Payload)
-		panic(err)
+		return 0, errors.Wrap(err, "unmarshaling received drain payload")
 	}
 
-	return payload.Value
+	return payload.Value, nil
 }
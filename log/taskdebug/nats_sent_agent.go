@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/pkg/errors"
 )
 
 type NATSMessageSentAgentMessage struct {
@@ -18,20 +19,24 @@ type NATSMessageSentAgentMessage struct {
 
 var _ log.Line = &NATSMessageSentAgentMessage{}
 
-func (m NATSMessageSentAgentMessage) GetArgument0String() string {
+func (m NATSMessageSentAgentMessage) GetArgument0String() (string, error) {
 	var payload struct {
 		Arguments []interface{} `json:"arguments"`
 	}
 
 	err := json.Unmarshal([]byte(m.Payload), &payload)
 	if err != nil {
-		fmt.Println(m.Payload)
-		panic(err)
+		return "", errors.Wrap(err, "unmarshaling agent message payload")
 	}
 
 	if len(payload.Arguments) < 1 {
-		panic("logical inconsistency: expected argument")
+		return "", fmt.Errorf("expected %s message to have an argument", m.PayloadMethod)
 	}
 
-	return payload.Arguments[0].(string)
+	arg0, ok := payload.Arguments[0].(string)
+	if !ok {
+		return "", fmt.Errorf("expected %s message to have a string argument", m.PayloadMethod)
+	}
+
+	return arg0, nil
 }
@@ -0,0 +1,83 @@
+package otlp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+const DefaultEndpoint = "http://localhost:4318/v1/traces"
+
+const (
+	EncodingProtobuf = "protobuf"
+	EncodingJSON     = "json"
+)
+
+// Exporter sends trace requests to an OTLP/HTTP receiver.
+type Exporter struct {
+	Endpoint string
+	Encoding string
+	Headers  map[string]string
+	Client   *http.Client
+}
+
+func (e Exporter) Export(req ExportTraceServiceRequest) error {
+	var body []byte
+	var contentType string
+
+	switch e.Encoding {
+	case EncodingProtobuf, "":
+		body = req.MarshalProto()
+		contentType = "application/x-protobuf"
+	case EncodingJSON:
+		var err error
+
+		body, err = json.Marshal(req)
+		if err != nil {
+			return fmt.Errorf("encoding request: %s", err)
+		}
+
+		contentType = "application/json"
+	default:
+		return fmt.Errorf("unsupported encoding: %s", e.Encoding)
+	}
+
+	endpoint := e.Endpoint
+	if endpoint == "" {
+		endpoint = DefaultEndpoint
+	}
+
+	httpReq, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("building request: %s", err)
+	}
+
+	httpReq.Header.Set("Content-Type", contentType)
+
+	for k, v := range e.Headers {
+		httpReq.Header.Set(k, v)
+	}
+
+	client := e.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	res, err := client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("exporting spans: %s", err)
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		resBody, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+
+		return fmt.Errorf("exporting spans: unexpected status %d: %s", res.StatusCode, bytes.TrimSpace(resBody))
+	}
+
+	return nil
+}
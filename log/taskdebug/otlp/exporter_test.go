@@ -0,0 +1,427 @@
+package otlp
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/dpb587/bosh-log-tracer/log/input"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug/parser"
+	"github.com/dpb587/bosh-log-tracer/observer/context"
+	"github.com/dpb587/bosh-log-tracer/pipeline"
+	"github.com/dpb587/bosh-log-tracer/trace"
+)
+
+// receivedSpan is a span as decoded by the receiver, independent of encoding.
+type receivedSpan struct {
+	service       string
+	traceID       string
+	spanID        string
+	parentSpanID  string
+	name          string
+	start         uint64
+	end           uint64
+	statusCode    uint64
+	statusMessage string
+	attributes    map[string]interface{}
+}
+
+type receivedRequest struct {
+	contentType string
+	header      string
+	resources   []map[string]interface{}
+	spans       []receivedSpan
+}
+
+func TestObserverExport(t *testing.T) {
+	for _, encoding := range []string{EncodingProtobuf, EncodingJSON} {
+		t.Run(encoding, func(t *testing.T) {
+			var received []receivedRequest
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := ioutil.ReadAll(r.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				req := receivedRequest{
+					contentType: r.Header.Get("Content-Type"),
+					header:      r.Header.Get("X-Token"),
+				}
+
+				if encoding == EncodingJSON {
+					decodeJSONRequest(t, body, &req)
+				} else {
+					decodeProtoRequest(t, body, &req)
+				}
+
+				received = append(received, req)
+			}))
+			defer server.Close()
+
+			obs := NewObserver(&context.Context{}, ObserverOptions{
+				Endpoint:     server.URL,
+				Encoding:     encoding,
+				Headers:      map[string]string{"X-Token": "secret"},
+				DirectorName: "my-director",
+			})
+
+			err := pipeline.Run(input.NewFileInput(filepath.Join("..", "testdata", "deploy-failed.log")), parser.NewParser(parser.Options{}), obs)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if len(received) != 1 {
+				t.Fatalf("expected 1 request, but got %d", len(received))
+			}
+
+			req := received[0]
+
+			expectedContentType := map[string]string{
+				EncodingProtobuf: "application/x-protobuf",
+				EncodingJSON:     "application/json",
+			}[encoding]
+
+			if req.contentType != expectedContentType {
+				t.Errorf("expected content type %q, but got %q", expectedContentType, req.contentType)
+			} else if req.header != "secret" {
+				t.Errorf("expected header to be sent, but got %q", req.header)
+			}
+
+			for _, attributes := range req.resources {
+				for key, expected := range map[string]interface{}{
+					"bosh.director.id":   "2184b994-fddd-fc27-99c8-76b183cdbc27",
+					"bosh.director.name": "my-director",
+					"bosh.deployment":    "deployment-6412ca06",
+				} {
+					if attributes[key] != expected {
+						t.Errorf("resource %v: expected %s of %q, but got %v", attributes["service.name"], key, expected, attributes[key])
+					}
+				}
+			}
+
+			assertSpans(t, obs.observer.Trace(), req.spans)
+		})
+	}
+}
+
+func TestExporterStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid spans\n"))
+	}))
+	defer server.Close()
+
+	err := Exporter{Endpoint: server.URL}.Export(ExportTraceServiceRequest{})
+	if err == nil {
+		t.Fatal("expected an error")
+	} else if expected := "exporting spans: unexpected status 400: invalid spans"; err.Error() != expected {
+		t.Fatalf("expected error %q, but got %q", expected, err)
+	}
+}
+
+// assertSpans compares the received spans with the finished spans of the trace.
+func assertSpans(t *testing.T, tr *trace.Trace, spans []receivedSpan) {
+	t.Helper()
+
+	expected := tr.FinishedSpans()
+
+	if len(spans) != len(expected) {
+		t.Fatalf("expected %d spans, but got %d", len(expected), len(spans))
+	}
+
+	byID := map[string]receivedSpan{}
+
+	for _, sp := range spans {
+		byID[sp.spanID] = sp
+	}
+
+	var roots, errors int
+
+	for _, sp := range expected {
+		id := hex.EncodeToString(spanID(sp.ID))
+
+		actual, found := byID[id]
+		if !found {
+			t.Errorf("%s: span %s was not received", sp.OperationName, id)
+
+			continue
+		}
+
+		if actual.service != sp.Service || actual.name != sp.OperationName {
+			t.Errorf("%s: expected %s: %s, but got %s: %s", id, sp.Service, sp.OperationName, actual.service, actual.name)
+		}
+
+		if actual.traceID != hex.EncodeToString(traceID(sp.TraceID)) || len(actual.traceID) != 32 {
+			t.Errorf("%s: unexpected trace ID %s", id, actual.traceID)
+		}
+
+		if sp.ParentID == 0 {
+			roots++
+
+			if actual.parentSpanID != "" {
+				t.Errorf("%s: expected no parent, but got %s", id, actual.parentSpanID)
+			}
+		} else if _, found := byID[actual.parentSpanID]; !found || actual.parentSpanID != hex.EncodeToString(spanID(sp.ParentID)) {
+			t.Errorf("%s: expected parent %x, but got %s", id, uint64(sp.ParentID), actual.parentSpanID)
+		}
+
+		if actual.start != uint64(sp.StartTime.UnixNano()) || actual.end != uint64(sp.FinishTime.UnixNano()) {
+			t.Errorf("%s: expected %d-%d, but got %d-%d", id, sp.StartTime.UnixNano(), sp.FinishTime.UnixNano(), actual.start, actual.end)
+		}
+
+		if sp.IsError() {
+			errors++
+
+			if actual.statusCode != StatusCodeError || actual.statusMessage != sp.Status.Message {
+				t.Errorf("%s: expected error status %q, but got %d %q", id, sp.Status.Message, actual.statusCode, actual.statusMessage)
+			}
+		} else if actual.statusCode != StatusCodeUnset {
+			t.Errorf("%s: expected unset status, but got %d", id, actual.statusCode)
+		}
+
+		for _, tag := range sp.Tags {
+			if v := actual.attributes[tag.Key]; v != tag.ScalarValue() {
+				t.Errorf("%s: expected attribute %s of %#v, but got %#v", id, tag.Key, tag.ScalarValue(), v)
+			}
+		}
+	}
+
+	if roots != 1 {
+		t.Errorf("expected 1 root span, but got %d", roots)
+	} else if errors == 0 {
+		t.Errorf("expected failed spans in the fixture")
+	}
+}
+
+func decodeJSONRequest(t *testing.T, body []byte, req *receivedRequest) {
+	type keyValue struct {
+		Key   string `json:"key"`
+		Value struct {
+			StringValue *string  `json:"stringValue"`
+			BoolValue   *bool    `json:"boolValue"`
+			IntValue    *string  `json:"intValue"`
+			DoubleValue *float64 `json:"doubleValue"`
+		} `json:"value"`
+	}
+
+	var decoded struct {
+		ResourceSpans []struct {
+			Resource struct {
+				Attributes []keyValue `json:"attributes"`
+			} `json:"resource"`
+			ScopeSpans []struct {
+				Scope struct {
+					Name string `json:"name"`
+				} `json:"scope"`
+				Spans []struct {
+					TraceID           string     `json:"traceId"`
+					SpanID            string     `json:"spanId"`
+					ParentSpanID      string     `json:"parentSpanId"`
+					Name              string     `json:"name"`
+					StartTimeUnixNano string     `json:"startTimeUnixNano"`
+					EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+					Attributes        []keyValue `json:"attributes"`
+					Status            struct {
+						Message string `json:"message"`
+						Code    uint64 `json:"code"`
+					} `json:"status"`
+				} `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+
+	err := json.Unmarshal(body, &decoded)
+	if err != nil {
+		t.Fatalf("decoding request: %s", err)
+	}
+
+	attributes := func(kvs []keyValue) map[string]interface{} {
+		res := map[string]interface{}{}
+
+		for _, kv := range kvs {
+			switch {
+			case kv.Value.StringValue != nil:
+				res[kv.Key] = *kv.Value.StringValue
+			case kv.Value.BoolValue != nil:
+				res[kv.Key] = *kv.Value.BoolValue
+			case kv.Value.IntValue != nil:
+				v, err := strconv.ParseInt(*kv.Value.IntValue, 10, 64)
+				if err != nil {
+					t.Fatalf("decoding %s: %s", kv.Key, err)
+				}
+
+				res[kv.Key] = v
+			case kv.Value.DoubleValue != nil:
+				res[kv.Key] = *kv.Value.DoubleValue
+			}
+		}
+
+		return res
+	}
+
+	uint64Str := func(s string) uint64 {
+		v, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			t.Fatalf("decoding time: %s", err)
+		}
+
+		return v
+	}
+
+	for _, rs := range decoded.ResourceSpans {
+		resource := attributes(rs.Resource.Attributes)
+		req.resources = append(req.resources, resource)
+
+		for _, ss := range rs.ScopeSpans {
+			if ss.Scope.Name != ScopeName {
+				t.Errorf("unexpected scope: %s", ss.Scope.Name)
+			}
+
+			for _, sp := range ss.Spans {
+				req.spans = append(req.spans, receivedSpan{
+					service:       resource["service.name"].(string),
+					traceID:       sp.TraceID,
+					spanID:        sp.SpanID,
+					parentSpanID:  sp.ParentSpanID,
+					name:          sp.Name,
+					start:         uint64Str(sp.StartTimeUnixNano),
+					end:           uint64Str(sp.EndTimeUnixNano),
+					statusCode:    sp.Status.Code,
+					statusMessage: sp.Status.Message,
+					attributes:    attributes(sp.Attributes),
+				})
+			}
+		}
+	}
+}
+
+// protoFields are the values of a decoded protobuf message by field number;
+// values are either uint64 (varint and fixed64) or []byte.
+type protoFields map[int][]interface{}
+
+func (f protoFields) bytes(field int) []byte {
+	if v := f[field]; len(v) > 0 {
+		return v[len(v)-1].([]byte)
+	}
+
+	return nil
+}
+
+func (f protoFields) uint(field int) uint64 {
+	if v := f[field]; len(v) > 0 {
+		return v[len(v)-1].(uint64)
+	}
+
+	return 0
+}
+
+func decodeProto(t *testing.T, b []byte) protoFields {
+	t.Helper()
+
+	res := protoFields{}
+
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			t.Fatalf("decoding key: invalid varint")
+		}
+
+		b = b[n:]
+		field := int(key >> 3)
+
+		switch key & 7 {
+		case wireVarint:
+			v, n := binary.Uvarint(b)
+			if n <= 0 {
+				t.Fatalf("decoding field %d: invalid varint", field)
+			}
+
+			res[field] = append(res[field], v)
+			b = b[n:]
+		case wireFixed64:
+			if len(b) < 8 {
+				t.Fatalf("decoding field %d: short fixed64", field)
+			}
+
+			res[field] = append(res[field], binary.LittleEndian.Uint64(b))
+			b = b[8:]
+		case wireBytes:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < l {
+				t.Fatalf("decoding field %d: invalid length", field)
+			}
+
+			res[field] = append(res[field], b[n:n+int(l)])
+			b = b[n+int(l):]
+		default:
+			t.Fatalf("decoding field %d: unexpected wire type %d", field, key&7)
+		}
+	}
+
+	return res
+}
+
+func decodeProtoRequest(t *testing.T, body []byte, req *receivedRequest) {
+	attributes := func(kvs []interface{}) map[string]interface{} {
+		res := map[string]interface{}{}
+
+		for _, kvU := range kvs {
+			kv := decodeProto(t, kvU.([]byte))
+			value := decodeProto(t, kv.bytes(2))
+			key := string(kv.bytes(1))
+
+			switch {
+			case value[1] != nil:
+				res[key] = string(value.bytes(1))
+			case value[2] != nil:
+				res[key] = value.uint(2) == 1
+			case value[3] != nil:
+				res[key] = int64(value.uint(3))
+			case value[4] != nil:
+				res[key] = math.Float64frombits(value.uint(4))
+			}
+		}
+
+		return res
+	}
+
+	for _, rsU := range decodeProto(t, body)[1] {
+		rs := decodeProto(t, rsU.([]byte))
+		resource := attributes(decodeProto(t, rs.bytes(1))[1])
+		req.resources = append(req.resources, resource)
+
+		for _, ssU := range rs[2] {
+			ss := decodeProto(t, ssU.([]byte))
+
+			if name := string(decodeProto(t, ss.bytes(1)).bytes(1)); name != ScopeName {
+				t.Errorf("unexpected scope: %s", name)
+			}
+
+			for _, spU := range ss[2] {
+				sp := decodeProto(t, spU.([]byte))
+				status := decodeProto(t, sp.bytes(15))
+
+				req.spans = append(req.spans, receivedSpan{
+					service:       resource["service.name"].(string),
+					traceID:       hex.EncodeToString(sp.bytes(1)),
+					spanID:        hex.EncodeToString(sp.bytes(2)),
+					parentSpanID:  hex.EncodeToString(sp.bytes(4)),
+					name:          string(sp.bytes(5)),
+					start:         sp.uint(7),
+					end:           sp.uint(8),
+					statusCode:    status.uint(3),
+					statusMessage: string(status.bytes(2)),
+					attributes:    attributes(sp[9]),
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package otlp
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strconv"
+
+	"github.com/dpb587/bosh-log-tracer/trace"
+)
+
+// The following types mirror the OTLP trace protobuf messages (v1) closely
+// enough to be encoded as either protobuf or the OTLP/JSON mapping.
+
+type ExportTraceServiceRequest struct {
+	ResourceSpans []ResourceSpans `json:"resourceSpans"`
+}
+
+type ResourceSpans struct {
+	Resource   Resource     `json:"resource"`
+	ScopeSpans []ScopeSpans `json:"scopeSpans"`
+}
+
+type Resource struct {
+	Attributes []KeyValue `json:"attributes,omitempty"`
+}
+
+type ScopeSpans struct {
+	Scope InstrumentationScope `json:"scope"`
+	Spans []Span               `json:"spans"`
+}
+
+type InstrumentationScope struct {
+	Name string `json:"name,omitempty"`
+}
+
+type Span struct {
+	TraceID           hexBytes   `json:"traceId"`
+	SpanID            hexBytes   `json:"spanId"`
+	ParentSpanID      hexBytes   `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              int        `json:"kind"`
+	StartTimeUnixNano uint64Str  `json:"startTimeUnixNano"`
+	EndTimeUnixNano   uint64Str  `json:"endTimeUnixNano"`
+	Attributes        []KeyValue `json:"attributes,omitempty"`
+	Events            []Event    `json:"events,omitempty"`
+	Status            Status     `json:"status"`
+}
+
+type Event struct {
+	TimeUnixNano uint64Str  `json:"timeUnixNano"`
+	Name         string     `json:"name"`
+	Attributes   []KeyValue `json:"attributes,omitempty"`
+}
+
+type Status struct {
+	Message string `json:"message,omitempty"`
+	Code    int    `json:"code,omitempty"`
+}
+
+type KeyValue struct {
+	Key   string   `json:"key"`
+	Value AnyValue `json:"value"`
+}
+
+type AnyValue struct {
+	StringValue *string   `json:"stringValue,omitempty"`
+	BoolValue   *bool     `json:"boolValue,omitempty"`
+	IntValue    *int64Str `json:"intValue,omitempty"`
+	DoubleValue *float64  `json:"doubleValue,omitempty"`
+	BytesValue  []byte    `json:"bytesValue,omitempty"`
+}
+
+const (
+	SpanKindInternal = 1
+
+	StatusCodeUnset = 0
+	StatusCodeError = 2
+)
+
+// ScopeName identifies the spans as coming from this tool.
+const ScopeName = "github.com/dpb587/bosh-log-tracer"
+
+// hexBytes are encoded as lowercase hex in OTLP/JSON (rather than the base64
+// default for protobuf bytes).
+type hexBytes []byte
+
+func (b hexBytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(hex.EncodeToString(b))
+}
+
+// 64-bit integers are encoded as strings in OTLP/JSON.
+type uint64Str uint64
+
+func (v uint64Str) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strconv.FormatUint(uint64(v), 10))
+}
+
+type int64Str int64
+
+func (v int64Str) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strconv.FormatInt(int64(v), 10))
+}
+
+// NewExportTraceServiceRequest groups the finished spans of a trace by their
+// service into resources which also carry the common resource attributes.
+func NewExportTraceServiceRequest(tr *trace.Trace, resourceAttributes []KeyValue) ExportTraceServiceRequest {
+	byService := map[string][]Span{}
+
+	for _, span := range tr.FinishedSpans() {
+		byService[span.Service] = append(byService[span.Service], newSpan(span))
+	}
+
+	var services []string
+
+	for service := range byService {
+		services = append(services, service)
+	}
+
+	sort.Strings(services)
+
+	var req ExportTraceServiceRequest
+
+	for _, service := range services {
+		attributes := append(
+			[]KeyValue{StringKeyValue("service.name", service)},
+			resourceAttributes...,
+		)
+
+		req.ResourceSpans = append(req.ResourceSpans, ResourceSpans{
+			Resource: Resource{
+				Attributes: attributes,
+			},
+			ScopeSpans: []ScopeSpans{
+				{
+					Scope: InstrumentationScope{Name: ScopeName},
+					Spans: byService[service],
+				},
+			},
+		})
+	}
+
+	return req
+}
+
+func newSpan(in *trace.Span) Span {
+	out := Span{
+		TraceID:           traceID(in.TraceID),
+		SpanID:            spanID(in.ID),
+		Name:              in.OperationName,
+		Kind:              SpanKindInternal,
+		StartTimeUnixNano: uint64Str(in.StartTime.UnixNano()),
+		EndTimeUnixNano:   uint64Str(in.FinishTime.UnixNano()),
+	}
+
+	if in.ParentID != 0 {
+		out.ParentSpanID = spanID(in.ParentID)
+	}
+
+	if in.IsError() {
+		out.Status = Status{
+			Code:    StatusCodeError,
+			Message: in.Status.Message,
+		}
+	}
+
+	for _, tag := range in.Tags {
+		out.Attributes = append(out.Attributes, newKeyValue(tag))
+	}
+
+	for _, event := range in.Events {
+		out.Events = append(out.Events, Event{
+			TimeUnixNano: uint64Str(event.Time.UnixNano()),
+			Name:         event.Name,
+			Attributes:   newKeyValues(event.Fields),
+		})
+	}
+
+	return out
+}
+
+func newKeyValues(tags []trace.Tag) []KeyValue {
+	var res []KeyValue
+
+	for _, tag := range tags {
+		res = append(res, newKeyValue(tag))
+	}
+
+	return res
+}
+
+func newKeyValue(tag trace.Tag) KeyValue {
+	kv := KeyValue{Key: tag.Key}
+
+	switch v := tag.ScalarValue().(type) {
+	case float64:
+		kv.Value.DoubleValue = &v
+	case bool:
+		kv.Value.BoolValue = &v
+	case int64:
+		i := int64Str(v)
+		kv.Value.IntValue = &i
+	case string:
+		kv.Value.StringValue = &v
+	}
+
+	return kv
+}
+
+func StringKeyValue(key, value string) KeyValue {
+	return KeyValue{
+		Key:   key,
+		Value: AnyValue{StringValue: &value},
+	}
+}
+
+func traceID(id trace.TraceID) hexBytes {
+	buf := make([]byte, 16)
+	binary.BigEndian.PutUint64(buf[0:8], id.High)
+	binary.BigEndian.PutUint64(buf[8:16], id.Low)
+
+	return buf
+}
+
+func spanID(id trace.SpanID) hexBytes {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, uint64(id))
+
+	return buf
+}
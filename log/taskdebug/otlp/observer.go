@@ -0,0 +1,118 @@
+package otlp
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug/timeline"
+	"github.com/dpb587/bosh-log-tracer/observer"
+	"github.com/dpb587/bosh-log-tracer/observer/context"
+	"github.com/dpb587/bosh-log-tracer/trace"
+)
+
+// Observer exports the timeline of a task to an OpenTelemetry receiver once it
+// has been committed.
+type Observer struct {
+	observer *timeline.Observer
+	exporter Exporter
+
+	directorName string
+	deployment   string
+}
+
+type ObserverOptions struct {
+	Timeline timeline.ObserverOptions
+
+	Endpoint string
+	Encoding string
+	Headers  map[string]string
+	Client   *http.Client
+
+	// DirectorName and Deployment are added as resource attributes; the
+	// deployment defaults to the one referenced by the task.
+	DirectorName string
+	Deployment   string
+}
+
+var _ observer.Observer = &Observer{}
+
+func NewObserver(ctx *context.Context, o ObserverOptions) *Observer {
+	if o.Endpoint == "" {
+		o.Endpoint = DefaultEndpoint
+	}
+
+	return &Observer{
+		observer: timeline.NewObserver(ctx, o.Timeline),
+		exporter: Exporter{
+			Endpoint: o.Endpoint,
+			Encoding: o.Encoding,
+			Headers:  o.Headers,
+			Client:   o.Client,
+		},
+		directorName: o.DirectorName,
+		deployment:   o.Deployment,
+	}
+}
+
+func (l *Observer) Begin() error {
+	return l.observer.Begin()
+}
+
+func (l *Observer) Handle(msg log.Line) error {
+	return l.observer.Handle(msg)
+}
+
+func (l *Observer) Flush() error {
+	return l.observer.Flush()
+}
+
+func (l *Observer) Commit() error {
+	err := l.observer.Commit()
+	if err != nil {
+		return err
+	}
+
+	tr := l.observer.Trace()
+
+	spans := tr.FinishedSpans()
+	if len(spans) == 0 {
+		return nil
+	}
+
+	err = l.exporter.Export(NewExportTraceServiceRequest(tr, l.getResourceAttributes(tr)))
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("exported %d spans to %s\n", len(spans), l.exporter.Endpoint)
+
+	return nil
+}
+
+func (l *Observer) getResourceAttributes(tr *trace.Trace) []KeyValue {
+	var res []KeyValue
+
+	directorName := l.directorName
+	deployment := l.deployment
+
+	if root, found := tr.RootSpan(); found {
+		if v, ok := root.GetTag("director.instance.id"); ok {
+			res = append(res, StringKeyValue("bosh.director.id", fmt.Sprintf("%v", v)))
+		}
+
+		if v, ok := root.GetTag("deployment"); ok && deployment == "" {
+			deployment = fmt.Sprintf("%v", v)
+		}
+	}
+
+	if directorName != "" {
+		res = append(res, StringKeyValue("bosh.director.name", directorName))
+	}
+
+	if deployment != "" {
+		res = append(res, StringKeyValue("bosh.deployment", deployment))
+	}
+
+	return res
+}
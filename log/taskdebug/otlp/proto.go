@@ -0,0 +1,187 @@
+package otlp
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// A minimal protobuf encoder for the handful of OTLP messages used here. Field
+// numbers follow opentelemetry/proto/trace/v1/trace.proto and
+// opentelemetry/proto/common/v1/common.proto.
+
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+)
+
+type protoBuffer []byte
+
+func (b *protoBuffer) key(field int, wireType int) {
+	b.varint(uint64(field)<<3 | uint64(wireType))
+}
+
+func (b *protoBuffer) varint(v uint64) {
+	for v >= 0x80 {
+		*b = append(*b, byte(v)|0x80)
+		v >>= 7
+	}
+
+	*b = append(*b, byte(v))
+}
+
+func (b *protoBuffer) bytes(field int, v []byte) {
+	if len(v) == 0 {
+		return
+	}
+
+	b.key(field, wireBytes)
+	b.varint(uint64(len(v)))
+	*b = append(*b, v...)
+}
+
+func (b *protoBuffer) string(field int, v string) {
+	b.bytes(field, []byte(v))
+}
+
+func (b *protoBuffer) uint(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+
+	b.key(field, wireVarint)
+	b.varint(v)
+}
+
+func (b *protoBuffer) fixed64(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+
+	b.key(field, wireFixed64)
+
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], v)
+	*b = append(*b, buf[:]...)
+}
+
+func (b *protoBuffer) message(field int, m protoMarshaler) {
+	var nested protoBuffer
+	m.marshalProto(&nested)
+
+	b.key(field, wireBytes)
+	b.varint(uint64(len(nested)))
+	*b = append(*b, nested...)
+}
+
+type protoMarshaler interface {
+	marshalProto(*protoBuffer)
+}
+
+// MarshalProto encodes the request in the protobuf wire format.
+func (r ExportTraceServiceRequest) MarshalProto() []byte {
+	var b protoBuffer
+	r.marshalProto(&b)
+
+	return b
+}
+
+func (r ExportTraceServiceRequest) marshalProto(b *protoBuffer) {
+	for _, rs := range r.ResourceSpans {
+		b.message(1, rs)
+	}
+}
+
+func (r ResourceSpans) marshalProto(b *protoBuffer) {
+	b.message(1, r.Resource)
+
+	for _, ss := range r.ScopeSpans {
+		b.message(2, ss)
+	}
+}
+
+func (r Resource) marshalProto(b *protoBuffer) {
+	for _, kv := range r.Attributes {
+		b.message(1, kv)
+	}
+}
+
+func (s ScopeSpans) marshalProto(b *protoBuffer) {
+	b.message(1, s.Scope)
+
+	for _, span := range s.Spans {
+		b.message(2, span)
+	}
+}
+
+func (s InstrumentationScope) marshalProto(b *protoBuffer) {
+	b.string(1, s.Name)
+}
+
+func (s Span) marshalProto(b *protoBuffer) {
+	b.bytes(1, s.TraceID)
+	b.bytes(2, s.SpanID)
+	b.bytes(4, s.ParentSpanID)
+	b.string(5, s.Name)
+	b.uint(6, uint64(s.Kind))
+	b.fixed64(7, uint64(s.StartTimeUnixNano))
+	b.fixed64(8, uint64(s.EndTimeUnixNano))
+
+	for _, kv := range s.Attributes {
+		b.message(9, kv)
+	}
+
+	for _, event := range s.Events {
+		b.message(11, event)
+	}
+
+	b.message(15, s.Status)
+}
+
+func (e Event) marshalProto(b *protoBuffer) {
+	b.fixed64(1, uint64(e.TimeUnixNano))
+	b.string(2, e.Name)
+
+	for _, kv := range e.Attributes {
+		b.message(3, kv)
+	}
+}
+
+func (s Status) marshalProto(b *protoBuffer) {
+	b.string(2, s.Message)
+	b.uint(3, uint64(s.Code))
+}
+
+func (kv KeyValue) marshalProto(b *protoBuffer) {
+	b.string(1, kv.Key)
+	b.message(2, kv.Value)
+}
+
+func (v AnyValue) marshalProto(b *protoBuffer) {
+	switch {
+	case v.StringValue != nil:
+		// written even when empty since it is part of a oneof
+		b.key(1, wireBytes)
+		b.varint(uint64(len(*v.StringValue)))
+		*b = append(*b, *v.StringValue...)
+	case v.BoolValue != nil:
+		b.key(2, wireVarint)
+
+		if *v.BoolValue {
+			b.varint(1)
+		} else {
+			b.varint(0)
+		}
+	case v.IntValue != nil:
+		b.key(3, wireVarint)
+		b.varint(uint64(*v.IntValue))
+	case v.DoubleValue != nil:
+		b.key(4, wireFixed64)
+
+		var buf [8]byte
+		binary.LittleEndian.PutUint64(buf[:], math.Float64bits(*v.DoubleValue))
+		*b = append(*b, buf[:]...)
+	case v.BytesValue != nil:
+		b.bytes(7, v.BytesValue)
+	}
+}
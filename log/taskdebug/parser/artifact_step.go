@@ -0,0 +1,58 @@
+package parser
+
+import (
+	"regexp"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug"
+)
+
+var ArtifactStepParser = artifactStepParser{}
+
+type artifactStepParser struct{}
+
+type artifactStepRE struct {
+	Step string
+	RE   *regexp.Regexp
+}
+
+var artifactStepREs = []artifactStepRE{
+	// Downloading remote release from https://bosh.io/d/github.com/cloudfoundry/bpm-release?v=1.1.0
+	{Step: "downloading", RE: regexp.MustCompile(`^Downloading remote (release|stemcell) from (.+)$`)},
+	// Extracting release archive
+	{Step: "extracting", RE: regexp.MustCompile(`^Extracting (release|stemcell)(?: archive| tarball)?(.*)$`)},
+	// Verifying release manifest
+	{Step: "verifying", RE: regexp.MustCompile(`^Verifying (release|stemcell)(?: manifest| checksum| tarball)?(.*)$`)},
+	// Checking if this release already exists
+	{Step: "checking", RE: regexp.MustCompile(`^Checking if this (release|stemcell) already exists(.*)$`)},
+	// Uploading stemcell bosh-aws-xen-hvm-ubuntu-xenial-go_agent/315.41 to the cloud
+	{Step: "uploading", RE: regexp.MustCompile(`^Uploading (release|stemcell) (.+) to the cloud$`)},
+	// Saving stemcell info
+	{Step: "saving", RE: regexp.MustCompile(`^Saving (release|stemcell)(.*)$`)},
+}
+
+func (p artifactStepParser) Parse(inU log.Line) (log.Line, error) {
+	in, ok := inU.(taskdebug.RawMessage)
+	if !ok {
+		return inU, nil
+	}
+
+	if in.Component != "DirectorJobRunner" {
+		return inU, nil
+	}
+
+	for _, stepRE := range artifactStepREs {
+		if m := stepRE.RE.FindStringSubmatch(in.Message); len(m) > 0 {
+			out := taskdebug.ArtifactStepMessage{
+				RawMessage: in,
+				Artifact:   m[1],
+				Step:       stepRE.Step,
+				Detail:     m[2],
+			}
+
+			return out, nil
+		}
+	}
+
+	return inU, nil
+}
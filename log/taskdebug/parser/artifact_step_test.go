@@ -0,0 +1,64 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug"
+)
+
+func TestArtifactStepParser(t *testing.T) {
+	runParserTests(t, ArtifactStepParser, []parserTest{
+		{
+			name: "downloading",
+			line: taskPrefix + "Downloading remote release from https://bosh.io/d/github.com/cloudfoundry/bpm-release?v=1.1.0",
+			expected: func(raw taskdebug.RawMessage) log.Line {
+				return taskdebug.ArtifactStepMessage{
+					RawMessage: raw,
+					Artifact:   "release",
+					Step:       "downloading",
+					Detail:     "https://bosh.io/d/github.com/cloudfoundry/bpm-release?v=1.1.0",
+				}
+			},
+		},
+		{
+			name: "extracting",
+			line: taskPrefix + "Extracting release archive",
+			expected: func(raw taskdebug.RawMessage) log.Line {
+				return taskdebug.ArtifactStepMessage{
+					RawMessage: raw,
+					Artifact:   "release",
+					Step:       "extracting",
+				}
+			},
+		},
+		{
+			name: "uploading",
+			line: taskPrefix + "Uploading stemcell bosh-aws-xen-hvm-ubuntu-xenial-go_agent/315.41 to the cloud",
+			expected: func(raw taskdebug.RawMessage) log.Line {
+				return taskdebug.ArtifactStepMessage{
+					RawMessage: raw,
+					Artifact:   "stemcell",
+					Step:       "uploading",
+					Detail:     "bosh-aws-xen-hvm-ubuntu-xenial-go_agent/315.41",
+				}
+			},
+		},
+		{
+			name: "saving",
+			line: taskPrefix + "Saving stemcell info",
+			expected: func(raw taskdebug.RawMessage) log.Line {
+				return taskdebug.ArtifactStepMessage{
+					RawMessage: raw,
+					Artifact:   "stemcell",
+					Step:       "saving",
+					Detail:     " info",
+				}
+			},
+		},
+		{
+			name: "other message",
+			line: taskPrefix + "Creating deployment plan",
+		},
+	})
+}
@@ -0,0 +1,50 @@
+package parser
+
+import (
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug"
+)
+
+var BlobstoreParser = blobstoreParser{}
+
+type blobstoreParser struct{}
+
+// [blobstore] creating '8a1f2a8c-0a3d-4b5e-9d3c-2e4b1f7a6c5d' start
+// [blobstore] creating '8a1f2a8c-0a3d-4b5e-9d3c-2e4b1f7a6c5d' (took 0.153206)
+var blobstoreOneRE = regexp.MustCompile(`^\[blobstore\] ([a-z ]+?) '([^']*)' (?:(start)|\(took ([\d\.]+)s?\))$`)
+
+func (p blobstoreParser) Parse(inU log.Line) (log.Line, error) {
+	in, ok := inU.(taskdebug.RawMessage)
+	if !ok {
+		return inU, nil
+	}
+
+	if in.Component != "DirectorJobRunner" {
+		return inU, nil
+	}
+
+	if m := blobstoreOneRE.FindStringSubmatch(in.Message); len(m) > 0 {
+		out := taskdebug.BlobstoreMessage{
+			RawMessage: in,
+			Action:     m[1],
+			ObjectID:   m[2],
+			Event:      "start",
+		}
+
+		if m[3] == "" {
+			out.Event = "finish"
+
+			if res, err := strconv.ParseFloat(m[4], 64); err == nil {
+				out.Duration = time.Duration(int64(res * float64(time.Second)))
+			}
+		}
+
+		return out, nil
+	}
+
+	return inU, nil
+}
@@ -0,0 +1,43 @@
+package parser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug"
+)
+
+func TestBlobstoreParser(t *testing.T) {
+	runParserTests(t, BlobstoreParser, []parserTest{
+		{
+			name: "start",
+			line: debugPrefix + "[blobstore] creating '8a1f2a8c-0a3d-4b5e-9d3c-2e4b1f7a6c5d' start",
+			expected: func(raw taskdebug.RawMessage) log.Line {
+				return taskdebug.BlobstoreMessage{
+					RawMessage: raw,
+					Action:     "creating",
+					ObjectID:   "8a1f2a8c-0a3d-4b5e-9d3c-2e4b1f7a6c5d",
+					Event:      "start",
+				}
+			},
+		},
+		{
+			name: "finish",
+			line: debugPrefix + "[blobstore] checking existence of '8a1f2a8c-0a3d-4b5e-9d3c-2e4b1f7a6c5d' (took 0.153206)",
+			expected: func(raw taskdebug.RawMessage) log.Line {
+				return taskdebug.BlobstoreMessage{
+					RawMessage: raw,
+					Action:     "checking existence of",
+					ObjectID:   "8a1f2a8c-0a3d-4b5e-9d3c-2e4b1f7a6c5d",
+					Event:      "finish",
+					Duration:   153206 * time.Microsecond,
+				}
+			},
+		},
+		{
+			name: "other message",
+			line: debugPrefix + "[blobstore] something else",
+		},
+	})
+}
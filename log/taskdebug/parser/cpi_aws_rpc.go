@@ -37,7 +37,7 @@ func (p cpiAWSRPCParser) Parse(inU log.Line) (log.Line, error) {
 		out.PayloadMethod = strings.SplitN(out.Payload, "(", 2)[0]
 
 		if res, err := strconv.ParseFloat(m[2], 64); err == nil {
-			out.Duration = time.Duration(int64(res * 1000000))
+			out.Duration = time.Duration(int64(res * float64(time.Second)))
 		}
 
 		if res, err := strconv.Atoi(m[1]); err == nil {
@@ -0,0 +1,48 @@
+package parser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug"
+)
+
+func TestCPIAWSRPCParser(t *testing.T) {
+	runParserTests(t, CPIAWSRPCParser, []parserTest{
+		{
+			name: "call",
+			line: `I, [2019-06-19T01:44:54.000000 #26935]  INFO -- [req_id cpi-308955]: [Aws::EC2::Client 200 1.069542 0 retries] run_instances(image_id:"ami-1")`,
+			expected: func(raw taskdebug.RawMessage) log.Line {
+				return taskdebug.CPIAWSRPCMessage{
+					RawMessage:    raw,
+					Correlation:   "cpi-308955",
+					Duration:      1069542 * time.Microsecond,
+					StatusCode:    200,
+					Retries:       0,
+					Payload:       `run_instances(image_id:"ami-1")`,
+					PayloadMethod: "run_instances",
+				}
+			},
+		},
+		{
+			name: "retried",
+			line: `I, [2019-06-19T01:44:54.000000 #26935]  INFO -- [req_id cpi-308955]: [Aws::EC2::Client 503 0.250000 2 retries] describe_instances(instance_ids:["i-0abc"])`,
+			expected: func(raw taskdebug.RawMessage) log.Line {
+				return taskdebug.CPIAWSRPCMessage{
+					RawMessage:    raw,
+					Correlation:   "cpi-308955",
+					Duration:      250 * time.Millisecond,
+					StatusCode:    503,
+					Retries:       2,
+					Payload:       `describe_instances(instance_ids:["i-0abc"])`,
+					PayloadMethod: "describe_instances",
+				}
+			},
+		},
+		{
+			name: "director message",
+			line: debugPrefix + "[Aws::EC2::Client 200 1.069542 0 retries] run_instances()",
+		},
+	})
+}
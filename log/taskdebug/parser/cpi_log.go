@@ -0,0 +1,86 @@
+package parser
+
+import (
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug"
+)
+
+var CPILogParser = cpiLogParser{}
+
+type cpiLogParser struct{}
+
+func (p cpiLogParser) Parse(inU log.Line) (log.Line, error) {
+	in, ok := inU.(taskdebug.RawMessage)
+	if !ok {
+		return inU, nil
+	}
+
+	if in.Component != "ExternalCpiLog" || in.Tags["req_id"] == "" {
+		return inU, nil
+	}
+
+	out := taskdebug.CPILogMessage{
+		RawMessage:  in,
+		Correlation: in.Tags["req_id"],
+	}
+
+	return out, nil
+}
+
+var CPIWaitParser = cpiWaitParser{}
+
+type cpiWaitParser struct{}
+
+// Waiting for instance i-0abc to be running
+var cpiWaitOneRE = regexp.MustCompile(`^Waiting for (.+) to be (\w+)$`)
+
+// instance i-0abc is now running, took 12.5s
+var cpiWaitTwoRE = regexp.MustCompile(`^(.+) is now (\w+), took ([\d\.]+)s$`)
+
+// Parse refines CPI log messages; raw messages are first parsed by CPILogParser
+// so the parser may also be used on its own.
+func (p cpiWaitParser) Parse(inU log.Line) (log.Line, error) {
+	if _, raw := inU.(taskdebug.RawMessage); raw {
+		var err error
+
+		inU, err = CPILogParser.Parse(inU)
+		if inU == nil || err != nil {
+			return inU, err
+		}
+	}
+
+	in, ok := inU.(taskdebug.CPILogMessage)
+	if !ok {
+		return inU, nil
+	}
+
+	if m := cpiWaitOneRE.FindStringSubmatch(in.Message); len(m) > 0 {
+		out := taskdebug.CPIWaitMessage{
+			CPILogMessage: in,
+			Event:         "start",
+			Resource:      m[1],
+			State:         m[2],
+		}
+
+		return out, nil
+	} else if m := cpiWaitTwoRE.FindStringSubmatch(in.Message); len(m) > 0 {
+		out := taskdebug.CPIWaitMessage{
+			CPILogMessage: in,
+			Event:         "finish",
+			Resource:      m[1],
+			State:         m[2],
+		}
+
+		if res, err := strconv.ParseFloat(m[3], 64); err == nil {
+			out.Duration = time.Duration(int64(res * float64(time.Second)))
+		}
+
+		return out, nil
+	}
+
+	return in, nil
+}
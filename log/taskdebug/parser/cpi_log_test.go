@@ -0,0 +1,80 @@
+package parser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug"
+)
+
+const cpiPrefix = "I, [2019-06-19T01:44:54.000000 #26935]  INFO -- [req_id cpi-308955]: "
+
+func TestCPILogParser(t *testing.T) {
+	runParserTests(t, CPILogParser, []parserTest{
+		{
+			name: "cpi message",
+			line: cpiPrefix + "Creating new instance with: {\"image_id\":\"ami-1\"}",
+			expected: func(raw taskdebug.RawMessage) log.Line {
+				return taskdebug.CPILogMessage{
+					RawMessage:  raw,
+					Correlation: "cpi-308955",
+				}
+			},
+		},
+		{
+			name: "director message",
+			line: debugPrefix + "Creating new instance with: {}",
+		},
+	})
+}
+
+func TestCPIWaitParser(t *testing.T) {
+	runParserTests(t, CPIWaitParser, []parserTest{
+		{
+			name: "start",
+			line: cpiPrefix + "Waiting for instance i-0abc to be running",
+			expected: func(raw taskdebug.RawMessage) log.Line {
+				return taskdebug.CPIWaitMessage{
+					CPILogMessage: taskdebug.CPILogMessage{
+						RawMessage:  raw,
+						Correlation: "cpi-308955",
+					},
+					Event:    "start",
+					Resource: "instance i-0abc",
+					State:    "running",
+				}
+			},
+		},
+		{
+			name: "finish",
+			line: cpiPrefix + "instance i-0abc is now running, took 12.5s",
+			expected: func(raw taskdebug.RawMessage) log.Line {
+				return taskdebug.CPIWaitMessage{
+					CPILogMessage: taskdebug.CPILogMessage{
+						RawMessage:  raw,
+						Correlation: "cpi-308955",
+					},
+					Event:    "finish",
+					Resource: "instance i-0abc",
+					State:    "running",
+					Duration: 12500 * time.Millisecond,
+				}
+			},
+		},
+		{
+			name: "other cpi message",
+			line: cpiPrefix + "Creating new instance with: {}",
+			expected: func(raw taskdebug.RawMessage) log.Line {
+				return taskdebug.CPILogMessage{
+					RawMessage:  raw,
+					Correlation: "cpi-308955",
+				}
+			},
+		},
+		{
+			name: "director message",
+			line: debugPrefix + "Waiting for instance i-0abc to be running",
+		},
+	})
+}
@@ -0,0 +1,55 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug"
+)
+
+var ErrorParser = errorParser{}
+
+type errorParser struct{}
+
+// Bosh::Director::AgentJobNotRunning: 'web/6318b9e7-8c72-4c4e-8769-e59abaa32297 (0)' is not running after update.
+// Bosh::Director::DeploymentPlan::InstanceGroupNotFoundError: Instance group 'web' not found
+var errorOneRE = regexp.MustCompile(`(Bosh::Director::(?:\w+::)*\w+(?:Error|NotRunning|Timeout|Cancelled))(?:: (.+))?$`)
+
+func (p errorParser) Parse(inU log.Line) (log.Line, error) {
+	in, ok := inU.(taskdebug.RawMessage)
+	if !ok {
+		return inU, nil
+	}
+
+	if in.LogLevel != "ERROR" && !strings.Contains(in.Message, "Bosh::Director::") {
+		// the parser is tried on every message; avoid the expensive expression
+		// for the many lines which are not an error
+		return inU, nil
+	}
+
+	if m := errorOneRE.FindStringSubmatch(in.Message); len(m) > 0 {
+		out := taskdebug.ErrorMessage{
+			RawMessage:   in,
+			ErrorType:    m[1],
+			ErrorMessage: m[2],
+			Backtrace:    in.Continuation,
+		}
+
+		if out.ErrorMessage == "" {
+			out.ErrorMessage = in.Message
+		}
+
+		return out, nil
+	} else if in.LogLevel == "ERROR" {
+		out := taskdebug.ErrorMessage{
+			RawMessage:   in,
+			ErrorMessage: in.Message,
+			Backtrace:    in.Continuation,
+		}
+
+		return out, nil
+	}
+
+	return inU, nil
+}
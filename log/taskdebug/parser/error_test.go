@@ -0,0 +1,75 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug"
+)
+
+func TestErrorParser(t *testing.T) {
+	runParserTests(t, ErrorParser, []parserTest{
+		{
+			name: "director error",
+			line: "E, [2019-06-19T01:45:09.100000 #26587] [task:80528] ERROR -- DirectorJobRunner: Bosh::Director::AgentJobNotRunning: 'web/6318b9e7-8c72-4c4e-8769-e59abaa32297 (0)' is not running after update.",
+			expected: func(raw taskdebug.RawMessage) log.Line {
+				return taskdebug.ErrorMessage{
+					RawMessage:   raw,
+					ErrorType:    "Bosh::Director::AgentJobNotRunning",
+					ErrorMessage: "'web/6318b9e7-8c72-4c4e-8769-e59abaa32297 (0)' is not running after update.",
+				}
+			},
+		},
+		{
+			name: "nested director error",
+			line: "E, [2019-06-19T01:45:09.100000 #26587] [task:80528] ERROR -- DirectorJobRunner: Bosh::Director::DeploymentPlan::InstanceGroupNotFoundError: Instance group 'web' not found",
+			expected: func(raw taskdebug.RawMessage) log.Line {
+				return taskdebug.ErrorMessage{
+					RawMessage:   raw,
+					ErrorType:    "Bosh::Director::DeploymentPlan::InstanceGroupNotFoundError",
+					ErrorMessage: "Instance group 'web' not found",
+				}
+			},
+		},
+		{
+			name: "director error without message",
+			line: "E, [2019-06-19T01:45:09.100000 #26587] [task:80528] ERROR -- DirectorJobRunner: Bosh::Director::TaskCancelled",
+			expected: func(raw taskdebug.RawMessage) log.Line {
+				return taskdebug.ErrorMessage{
+					RawMessage:   raw,
+					ErrorType:    "Bosh::Director::TaskCancelled",
+					ErrorMessage: "Bosh::Director::TaskCancelled",
+				}
+			},
+		},
+		{
+			name: "director error with backtrace",
+			line: "E, [2019-06-19T01:45:09.100000 #26587] [task:80528] ERROR -- DirectorJobRunner: Bosh::Director::AgentJobNotRunning: 'web/6318b9e7-8c72-4c4e-8769-e59abaa32297 (0)' is not running after update.\n/var/vcap/packages/director/lib/bosh/director/instance_updater.rb:87:in `update'\n/var/vcap/packages/director/lib/bosh/director/jobs/update_deployment.rb:84:in `perform'",
+			expected: func(raw taskdebug.RawMessage) log.Line {
+				return taskdebug.ErrorMessage{
+					RawMessage:   raw,
+					ErrorType:    "Bosh::Director::AgentJobNotRunning",
+					ErrorMessage: "'web/6318b9e7-8c72-4c4e-8769-e59abaa32297 (0)' is not running after update.",
+					Backtrace: []string{
+						"/var/vcap/packages/director/lib/bosh/director/instance_updater.rb:87:in `update'",
+						"/var/vcap/packages/director/lib/bosh/director/jobs/update_deployment.rb:84:in `perform'",
+					},
+				}
+			},
+		},
+		{
+			name: "error level",
+			line: "E, [2019-06-19T01:45:09.000000 #26587] [task:80528] ERROR -- DirectorJobRunner: Something unexpected happened",
+			expected: func(raw taskdebug.RawMessage) log.Line {
+				return taskdebug.ErrorMessage{
+					RawMessage:   raw,
+					ErrorMessage: "Something unexpected happened",
+				}
+			},
+		},
+		{
+			name: "other message",
+			line: taskPrefix + "Updating deployment",
+		},
+	})
+}
@@ -4,8 +4,9 @@ import (
 	"encoding/json"
 	"regexp"
 
-	"github.com/dpb587/bosh-log-tracer/log/taskdebug"
 	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug"
+	"github.com/pkg/errors"
 )
 
 var ExternalCPIRequestParser = externalCPIRequestParser{}
@@ -15,10 +16,16 @@ type externalCPIRequestParser struct{}
 // [external-cpi] [cpi-308955] request: {"method":"create_vm","arguments":[...
 var externalCPIRequestOneRE = regexp.MustCompile(`(\{.+\}) with command: (.+)$`)
 
+// Parse refines external CPI messages; raw messages are first parsed by
+// ExternalCPIParser so the parser may also be used on its own.
 func (p externalCPIRequestParser) Parse(inU log.Line) (log.Line, error) {
-	inU, err := ExternalCPIParser.Parse(inU)
-	if inU == nil || err != nil {
-		return inU, err
+	if _, raw := inU.(taskdebug.RawMessage); raw {
+		var err error
+
+		inU, err = ExternalCPIParser.Parse(inU)
+		if inU == nil || err != nil {
+			return inU, err
+		}
 	}
 
 	in, ok := inU.(taskdebug.ExternalCPIMessage)
@@ -26,21 +33,14 @@ func (p externalCPIRequestParser) Parse(inU log.Line) (log.Line, error) {
 		return inU, nil
 	}
 
-	upstreamU, err := ExternalCPIParser.Parse(in)
-	if upstreamU == nil || err != nil {
-		return upstreamU, err
-	}
-
-	upstream := upstreamU.(taskdebug.ExternalCPIMessage)
-
-	if upstream.Event != "request" {
-		return upstream, nil
+	if in.Event != "request" {
+		return in, nil
 	}
 
-	if m := externalCPIRequestOneRE.FindStringSubmatch(upstream.Remaining); len(m) > 0 {
+	if m := externalCPIRequestOneRE.FindStringSubmatch(in.Remaining); len(m) > 0 {
 		out := taskdebug.ExternalCPIRequestMessage{
-			ExternalCPIMessage: upstream,
-			Payload: m[1],
+			ExternalCPIMessage: in,
+			Payload:            m[1],
 			Command:            m[2],
 		}
 
@@ -48,9 +48,9 @@ func (p externalCPIRequestParser) Parse(inU log.Line) (log.Line, error) {
 			Method string `json:"method"`
 		}
 
-		err = json.Unmarshal([]byte(out.Payload), &payload)
+		err := json.Unmarshal([]byte(out.Payload), &payload)
 		if err != nil {
-			panic(err)
+			return nil, log.NewLineError(in, errors.Wrap(err, "unmarshaling external cpi request payload"))
 		}
 
 		out.PayloadMethod = payload.Method
@@ -58,5 +58,5 @@ func (p externalCPIRequestParser) Parse(inU log.Line) (log.Line, error) {
 		return out, nil
 	}
 
-	return upstreamU, nil
+	return in, nil
 }
@@ -0,0 +1,51 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug"
+)
+
+func TestExternalCPIRequestParser(t *testing.T) {
+	runParserTests(t, ExternalCPIRequestParser, []parserTest{
+		{
+			name: "request",
+			line: debugPrefix + `[external-cpi] [cpi-308955] request: {"method":"create_vm","arguments":["agent-1",{"ami":"ami-1"}],"context":{"director_uuid":"abc"}} with command: /var/vcap/jobs/aws_cpi/bin/cpi`,
+			expected: func(raw taskdebug.RawMessage) log.Line {
+				return taskdebug.ExternalCPIRequestMessage{
+					ExternalCPIMessage: taskdebug.ExternalCPIMessage{
+						RawMessage:  raw,
+						Correlation: "cpi-308955",
+						Event:       "request",
+						Remaining:   `{"method":"create_vm","arguments":["agent-1",{"ami":"ami-1"}],"context":{"director_uuid":"abc"}} with command: /var/vcap/jobs/aws_cpi/bin/cpi`,
+					},
+					Payload:       `{"method":"create_vm","arguments":["agent-1",{"ami":"ami-1"}],"context":{"director_uuid":"abc"}}`,
+					PayloadMethod: "create_vm",
+					Command:       "/var/vcap/jobs/aws_cpi/bin/cpi",
+				}
+			},
+		},
+		{
+			name: "response",
+			line: debugPrefix + `[external-cpi] [cpi-308955] response: {"result":"i-0abc","error":null,"log":""}, err: , exit_status: pid 1234 exit 0`,
+			expected: func(raw taskdebug.RawMessage) log.Line {
+				return taskdebug.ExternalCPIMessage{
+					RawMessage:  raw,
+					Correlation: "cpi-308955",
+					Event:       "response",
+					Remaining:   `{"result":"i-0abc","error":null,"log":""}, err: , exit_status: pid 1234 exit 0`,
+				}
+			},
+		},
+		{
+			name:        "invalid payload",
+			line:        debugPrefix + `[external-cpi] [cpi-308955] request: {"method":create_vm} with command: /var/vcap/jobs/aws_cpi/bin/cpi`,
+			expectedErr: "test.log:1: unmarshaling external cpi request payload: invalid character 'c' looking for beginning of value",
+		},
+		{
+			name: "other message",
+			line: debugPrefix + "Acquiring lock: lock:deployment:concourse",
+		},
+	})
+}
@@ -0,0 +1,82 @@
+package parser
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug"
+	"github.com/pkg/errors"
+)
+
+var ExternalCPIResponseParser = externalCPIResponseParser{}
+
+type externalCPIResponseParser struct{}
+
+// [external-cpi] [cpi-308955] response: {"result":"i-0abc","error":null,"log":""}, err: , exit_status: pid 1234 exit 0
+var externalCPIResponseOneRE = regexp.MustCompile(`(?s)^, err: (.*), exit_status: ([^,]*)$`)
+
+// Parse refines external CPI messages; raw messages are first parsed by
+// ExternalCPIParser so the parser may also be used on its own.
+func (p externalCPIResponseParser) Parse(inU log.Line) (log.Line, error) {
+	if _, raw := inU.(taskdebug.RawMessage); raw {
+		var err error
+
+		inU, err = ExternalCPIParser.Parse(inU)
+		if inU == nil || err != nil {
+			return inU, err
+		}
+	}
+
+	in, ok := inU.(taskdebug.ExternalCPIMessage)
+	if !ok {
+		return inU, nil
+	}
+
+	if in.Event != "response" {
+		return in, nil
+	}
+
+	// stderr may span several lines
+	remaining := strings.Join(append([]string{in.Remaining}, in.Continuation...), "\n")
+
+	var payload struct {
+		Result interface{} `json:"result"`
+		Error  *struct {
+			Type      string `json:"type"`
+			Message   string `json:"message"`
+			OkToRetry bool   `json:"ok_to_retry"`
+		} `json:"error"`
+		Log string `json:"log"`
+	}
+
+	dec := json.NewDecoder(strings.NewReader(remaining))
+
+	err := dec.Decode(&payload)
+	if err != nil {
+		return nil, log.NewLineError(in, errors.Wrap(err, "unmarshaling external cpi response payload"))
+	}
+
+	offset := dec.InputOffset()
+
+	out := taskdebug.ExternalCPIResponseMessage{
+		ExternalCPIMessage: in,
+		Payload:            remaining[:offset],
+		Result:             payload.Result,
+		Log:                payload.Log,
+	}
+
+	if payload.Error != nil {
+		out.ErrorType = payload.Error.Type
+		out.ErrorMessage = payload.Error.Message
+		out.ErrorOkToRetry = payload.Error.OkToRetry
+	}
+
+	if m := externalCPIResponseOneRE.FindStringSubmatch(remaining[offset:]); len(m) > 0 {
+		out.Stderr = m[1]
+		out.ExitStatus = m[2]
+	}
+
+	return out, nil
+}
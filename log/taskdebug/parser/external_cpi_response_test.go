@@ -0,0 +1,105 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug"
+)
+
+func TestExternalCPIResponseParser(t *testing.T) {
+	runParserTests(t, ExternalCPIResponseParser, []parserTest{
+		{
+			name: "result",
+			line: debugPrefix + `[external-cpi] [cpi-308955] response: {"result":"vol-0abc","error":null,"log":"creating disk\n"}, err: , exit_status: pid 1234 exit 0`,
+			expected: func(raw taskdebug.RawMessage) log.Line {
+				return taskdebug.ExternalCPIResponseMessage{
+					ExternalCPIMessage: taskdebug.ExternalCPIMessage{
+						RawMessage:  raw,
+						Correlation: "cpi-308955",
+						Event:       "response",
+						Remaining:   `{"result":"vol-0abc","error":null,"log":"creating disk\n"}, err: , exit_status: pid 1234 exit 0`,
+					},
+					Payload:    `{"result":"vol-0abc","error":null,"log":"creating disk\n"}`,
+					Result:     "vol-0abc",
+					Log:        "creating disk\n",
+					ExitStatus: "pid 1234 exit 0",
+				}
+			},
+		},
+		{
+			name: "error",
+			line: debugPrefix + `[external-cpi] [cpi-308955] response: {"result":null,"error":{"type":"Bosh::Clouds::VMCreationFailed","message":"VM creation failed","ok_to_retry":true},"log":""}, err: Address is in use., exit_status: pid 1234 exit 0`,
+			expected: func(raw taskdebug.RawMessage) log.Line {
+				return taskdebug.ExternalCPIResponseMessage{
+					ExternalCPIMessage: taskdebug.ExternalCPIMessage{
+						RawMessage:  raw,
+						Correlation: "cpi-308955",
+						Event:       "response",
+						Remaining:   `{"result":null,"error":{"type":"Bosh::Clouds::VMCreationFailed","message":"VM creation failed","ok_to_retry":true},"log":""}, err: Address is in use., exit_status: pid 1234 exit 0`,
+					},
+					Payload:        `{"result":null,"error":{"type":"Bosh::Clouds::VMCreationFailed","message":"VM creation failed","ok_to_retry":true},"log":""}`,
+					ErrorType:      "Bosh::Clouds::VMCreationFailed",
+					ErrorMessage:   "VM creation failed",
+					ErrorOkToRetry: true,
+					Stderr:         "Address is in use.",
+					ExitStatus:     "pid 1234 exit 0",
+				}
+			},
+		},
+		{
+			name: "multiline stderr",
+			line: debugPrefix + "[external-cpi] [cpi-308955] response: {\"result\":null,\"error\":null,\"log\":\"\"}, err: first\nsecond, exit_status: pid 1234 exit 0",
+			expected: func(raw taskdebug.RawMessage) log.Line {
+				return taskdebug.ExternalCPIResponseMessage{
+					ExternalCPIMessage: taskdebug.ExternalCPIMessage{
+						RawMessage:  raw,
+						Correlation: "cpi-308955",
+						Event:       "response",
+						Remaining:   `{"result":null,"error":null,"log":""}, err: first`,
+					},
+					Payload:    `{"result":null,"error":null,"log":""}`,
+					Stderr:     "first\nsecond",
+					ExitStatus: "pid 1234 exit 0",
+				}
+			},
+		},
+		{
+			name: "request",
+			line: debugPrefix + `[external-cpi] [cpi-308955] request: {"method":"delete_vm","arguments":["i-0abc"]} with command: /var/vcap/jobs/aws_cpi/bin/cpi`,
+			expected: func(raw taskdebug.RawMessage) log.Line {
+				return taskdebug.ExternalCPIMessage{
+					RawMessage:  raw,
+					Correlation: "cpi-308955",
+					Event:       "request",
+					Remaining:   `{"method":"delete_vm","arguments":["i-0abc"]} with command: /var/vcap/jobs/aws_cpi/bin/cpi`,
+				}
+			},
+		},
+		{
+			name:        "invalid payload",
+			line:        debugPrefix + `[external-cpi] [cpi-308955] response: {"result":i-0abc}, err: , exit_status: pid 1234 exit 0`,
+			expectedErr: "test.log:1: unmarshaling external cpi response payload: invalid character 'i' looking for beginning of value",
+		},
+	})
+}
+
+func TestExternalCPIResponseMessageGetResultCID(t *testing.T) {
+	tests := []struct {
+		result   interface{}
+		expected string
+	}{
+		{result: "vol-0abc", expected: "vol-0abc"},
+		{result: []interface{}{"i-0abc", map[string]interface{}{}}, expected: "i-0abc"},
+		{result: nil},
+		{result: []interface{}{}},
+		{result: true},
+	}
+
+	for _, tt := range tests {
+		actual, ok := taskdebug.ExternalCPIResponseMessage{Result: tt.result}.GetResultCID()
+		if actual != tt.expected || ok != (tt.expected != "") {
+			t.Errorf("%#v: expected %q, but got %q (%v)", tt.result, tt.expected, actual, ok)
+		}
+	}
+}
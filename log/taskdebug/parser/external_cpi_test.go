@@ -0,0 +1,29 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug"
+)
+
+func TestExternalCPIParser(t *testing.T) {
+	runParserTests(t, ExternalCPIParser, []parserTest{
+		{
+			name: "response",
+			line: debugPrefix + `[external-cpi] [cpi-308955] response: {"result":"i-0abc","error":null,"log":""}, err: , exit_status: pid 1234 exit 0`,
+			expected: func(raw taskdebug.RawMessage) log.Line {
+				return taskdebug.ExternalCPIMessage{
+					RawMessage:  raw,
+					Correlation: "cpi-308955",
+					Event:       "response",
+					Remaining:   `{"result":"i-0abc","error":null,"log":""}, err: , exit_status: pid 1234 exit 0`,
+				}
+			},
+		},
+		{
+			name: "other message",
+			line: debugPrefix + "Acquiring lock: lock:deployment:concourse",
+		},
+	})
+}
@@ -0,0 +1,49 @@
+package parser
+
+import (
+	"flag"
+	"fmt"
+	"regexp"
+	"runtime"
+	"time"
+)
+
+// Flags are the parsing options shared by commands.
+type Flags struct {
+	sourceZone string
+	workers    int
+}
+
+func (f *Flags) Register(fs *flag.FlagSet) {
+	fs.StringVar(&f.sourceZone, "source-zone", "UTC", "zone of log timestamps which do not include an offset; a name (e.g. America/Denver or Local) or an offset (e.g. -07:00)")
+	fs.IntVar(&f.workers, "parallel", runtime.NumCPU(), "number of lines to parse concurrently")
+}
+
+// Workers is the number of lines to parse concurrently.
+func (f *Flags) Workers() int {
+	return f.workers
+}
+
+// +02:00, -0700
+var zoneOffsetRE = regexp.MustCompile(`^[+-]\d{2}:?\d{2}$`)
+
+// Options builds parser options from the parsed flags.
+func (f *Flags) Options() (Options, error) {
+	if zoneOffsetRE.MatchString(f.sourceZone) {
+		t, err := time.Parse("-07:00", f.sourceZone[0:3]+":"+f.sourceZone[len(f.sourceZone)-2:])
+		if err != nil {
+			return Options{}, fmt.Errorf("parsing source zone %s: %s", f.sourceZone, err)
+		}
+
+		_, offset := t.Zone()
+
+		return Options{Location: time.FixedZone(f.sourceZone, offset)}, nil
+	}
+
+	location, err := time.LoadLocation(f.sourceZone)
+	if err != nil {
+		return Options{}, fmt.Errorf("loading source zone %s: %s", f.sourceZone, err)
+	}
+
+	return Options{Location: location}, nil
+}
@@ -0,0 +1,48 @@
+package parser
+
+import (
+	"flag"
+	"testing"
+	"time"
+)
+
+func TestFlagsOptions(t *testing.T) {
+	tests := []struct {
+		args           []string
+		expectedOffset int
+		expectedErr    bool
+	}{
+		{args: nil, expectedOffset: 0},
+		{args: []string{"-source-zone", "+02:00"}, expectedOffset: 2 * 60 * 60},
+		{args: []string{"-source-zone", "-0730"}, expectedOffset: -(7*60 + 30) * 60},
+		{args: []string{"-source-zone", "Not/AZone"}, expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		f := &Flags{}
+
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		f.Register(fs)
+
+		err := fs.Parse(tt.args)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		options, err := f.Options()
+		if tt.expectedErr {
+			if err == nil {
+				t.Errorf("%v: expected an error", tt.args)
+			}
+
+			continue
+		} else if err != nil {
+			t.Fatalf("%v: unexpected error: %s", tt.args, err)
+		}
+
+		_, offset := time.Date(2019, 6, 19, 0, 0, 0, 0, options.Location).Zone()
+		if offset != tt.expectedOffset {
+			t.Errorf("%v: expected offset %d, but got %d", tt.args, tt.expectedOffset, offset)
+		}
+	}
+}
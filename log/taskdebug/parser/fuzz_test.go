@@ -0,0 +1,34 @@
+package parser
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/input"
+)
+
+// FuzzParser checks every parser copes with arbitrary lines, seeded by the
+// lines of the fixtures (go test -fuzz=FuzzParser ./log/taskdebug/parser).
+func FuzzParser(f *testing.F) {
+	paths, err := filepath.Glob(filepath.Join("..", "testdata", "*.log"))
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	for _, path := range paths {
+		err := input.NewFileInput(path).Scan(func(l log.Line) error {
+			f.Add(l.LineData())
+
+			return nil
+		})
+		if err != nil {
+			f.Fatal(err)
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, data string) {
+		// errors are expected for malformed lines; only panics are a problem
+		NewParser(Options{}).Parse(newRawLine(data))
+	})
+}
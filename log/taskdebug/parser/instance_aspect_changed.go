@@ -2,6 +2,7 @@ package parser
 
 import (
 	"regexp"
+	"strings"
 
 	"github.com/dpb587/bosh-log-tracer/log"
 	"github.com/dpb587/bosh-log-tracer/log/taskdebug"
@@ -24,6 +25,12 @@ func (p instanceAspectChangedParser) Parse(inU log.Line) (log.Line, error) {
 		return inU, nil
 	}
 
+	if !strings.Contains(in.Message, "_changed? changed FROM: ") {
+		// the message has no fixed prefix to be dispatched on; avoid the
+		// expensive expression for the many lines which are not a change
+		return inU, nil
+	}
+
 	if m := instanceAspectChangedOneRE.FindStringSubmatch(in.Message); len(m) > 0 {
 		msg := taskdebug.InstanceAspectChangedMessage{
 			RawMessage:    in,
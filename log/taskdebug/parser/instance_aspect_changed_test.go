@@ -0,0 +1,32 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug"
+)
+
+func TestInstanceAspectChangedParser(t *testing.T) {
+	runParserTests(t, InstanceAspectChangedParser, []parserTest{
+		{
+			name: "stemcell",
+			line: debugPrefix + "stemcell_changed? changed FROM: version: 315.36 TO: version: 315.41 on instance web/6318b9e7-8c72-4c4e-8769-e59abaa32297 (0)",
+			expected: func(raw taskdebug.RawMessage) log.Line {
+				return taskdebug.InstanceAspectChangedMessage{
+					RawMessage:    raw,
+					InstanceGroup: "web",
+					InstanceID:    "6318b9e7-8c72-4c4e-8769-e59abaa32297",
+					InstanceIndex: "0",
+					Aspect:        "stemcell",
+					ChangedFrom:   "version: 315.36",
+					ChangedTo:     "version: 315.41",
+				}
+			},
+		},
+		{
+			name: "other message",
+			line: debugPrefix + "Updating instance web/6318b9e7-8c72-4c4e-8769-e59abaa32297 (0) (canary)",
+		},
+	})
+}
@@ -0,0 +1,40 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug"
+)
+
+func TestLockParser(t *testing.T) {
+	runParserTests(t, LockParser, []parserTest{
+		{
+			name: "acquiring",
+			line: debugPrefix + "Acquiring lock: lock:deployment:concourse",
+			expected: func(raw taskdebug.RawMessage) log.Line {
+				return taskdebug.LockMessage{
+					RawMessage: raw,
+					Event:      "Acquiring",
+					Name:       "lock:deployment:concourse",
+				}
+			},
+		},
+		{
+			name: "deleted",
+			line: debugPrefix + "Deleted lock: lock:deployment:concourse uid: 3366af32-333e-453c-a73d-e2d7730071ba",
+			expected: func(raw taskdebug.RawMessage) log.Line {
+				return taskdebug.LockMessage{
+					RawMessage: raw,
+					Event:      "Deleted",
+					Name:       "lock:deployment:concourse",
+					UID:        "3366af32-333e-453c-a73d-e2d7730071ba",
+				}
+			},
+		},
+		{
+			name: "other message",
+			line: debugPrefix + "Lock renewal thread exiting",
+		},
+	})
+}
@@ -1,7 +1,7 @@
 package parser
 
 import (
-	"regexp"
+	"strings"
 
 	"github.com/dpb587/bosh-log-tracer/log"
 	"github.com/dpb587/bosh-log-tracer/log/taskdebug"
@@ -12,8 +12,9 @@ var NATSMessageParser = natsMessageParser{}
 type natsMessageParser struct{}
 
 // SENT: agent.0e2a1093-0ace-4685-a361-a6f40a11f7ed {"protocol":3,"method":"get_state",...
-var natsMessageOneRE = regexp.MustCompile(`^(SENT|RECEIVED): ([^ ]+) (.+)$`)
-
+//
+// Messages are cut rather than matched by an expression since payloads are long
+// and most lines of a task log are NATS messages.
 func (p natsMessageParser) Parse(inU log.Line) (log.Line, error) {
 	in, ok := inU.(taskdebug.RawMessage)
 	if !ok {
@@ -24,16 +25,22 @@ func (p natsMessageParser) Parse(inU log.Line) (log.Line, error) {
 		return inU, nil
 	}
 
-	if m := natsMessageOneRE.FindStringSubmatch(in.Message); len(m) > 0 {
-		out := taskdebug.NATSMessageMessage{
-			RawMessage: in,
-			Event:      m[1],
-			Channel:    m[2],
-			Payload:    m[3],
-		}
+	event, remaining, found := strings.Cut(in.Message, ": ")
+	if !found || (event != "SENT" && event != "RECEIVED") {
+		return inU, nil
+	}
+
+	channel, payload, found := strings.Cut(remaining, " ")
+	if !found || channel == "" || payload == "" {
+		return inU, nil
+	}
 
-		return out, nil
+	out := taskdebug.NATSMessageMessage{
+		RawMessage: in,
+		Event:      event,
+		Channel:    channel,
+		Payload:    payload,
 	}
 
-	return inU, nil
+	return out, nil
 }
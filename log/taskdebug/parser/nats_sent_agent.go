@@ -6,16 +6,23 @@ import (
 
 	"github.com/dpb587/bosh-log-tracer/log"
 	"github.com/dpb587/bosh-log-tracer/log/taskdebug"
+	"github.com/pkg/errors"
 )
 
 var NATSMessageSentAgentParser = natsMessageSentAgentParser{}
 
 type natsMessageSentAgentParser struct{}
 
+// Parse refines NATS messages; raw messages are first parsed by
+// NATSMessageParser so the parser may also be used on its own.
 func (p natsMessageSentAgentParser) Parse(inU log.Line) (log.Line, error) {
-	inU, err := NATSMessageParser.Parse(inU)
-	if inU == nil || err != nil {
-		return inU, err
+	if _, raw := inU.(taskdebug.RawMessage); raw {
+		var err error
+
+		inU, err = NATSMessageParser.Parse(inU)
+		if inU == nil || err != nil {
+			return inU, err
+		}
 	}
 
 	in, ok := inU.(taskdebug.NATSMessageMessage)
@@ -38,9 +45,9 @@ func (p natsMessageSentAgentParser) Parse(inU log.Line) (log.Line, error) {
 		ReplyTo  string `json:"reply_to"`
 	}
 
-	err = json.Unmarshal([]byte(out.Payload), &payload)
+	err := json.Unmarshal([]byte(out.Payload), &payload)
 	if err != nil {
-		panic(err)
+		return nil, log.NewLineError(in, errors.Wrap(err, "unmarshaling agent message payload"))
 	}
 
 	out.PayloadProtocol = payload.Protocol
@@ -0,0 +1,52 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug"
+)
+
+func TestNATSMessageSentAgentParser(t *testing.T) {
+	runParserTests(t, NATSMessageSentAgentParser, []parserTest{
+		{
+			name: "sent to agent",
+			line: debugPrefix + `SENT: agent.0e2a1093-0ace-4685-a361-a6f40a11f7ed {"protocol":3,"method":"get_task","arguments":["task-1"],"reply_to":"director.abc.2"}`,
+			expected: func(raw taskdebug.RawMessage) log.Line {
+				return taskdebug.NATSMessageSentAgentMessage{
+					NATSMessageMessage: taskdebug.NATSMessageMessage{
+						RawMessage: raw,
+						Event:      "SENT",
+						Channel:    "agent.0e2a1093-0ace-4685-a361-a6f40a11f7ed",
+						Payload:    `{"protocol":3,"method":"get_task","arguments":["task-1"],"reply_to":"director.abc.2"}`,
+					},
+					AgentID:         "0e2a1093-0ace-4685-a361-a6f40a11f7ed",
+					PayloadProtocol: 3,
+					PayloadMethod:   "get_task",
+					PayloadReplyTo:  "director.abc.2",
+				}
+			},
+		},
+		{
+			name: "sent elsewhere",
+			line: debugPrefix + `SENT: hm.director.alert {"id":"abc"}`,
+			expected: func(raw taskdebug.RawMessage) log.Line {
+				return taskdebug.NATSMessageMessage{
+					RawMessage: raw,
+					Event:      "SENT",
+					Channel:    "hm.director.alert",
+					Payload:    `{"id":"abc"}`,
+				}
+			},
+		},
+		{
+			name:        "invalid payload",
+			line:        debugPrefix + `SENT: agent.0e2a1093-0ace-4685-a361-a6f40a11f7ed {"protocol":3,"method":"get_ta`,
+			expectedErr: "test.log:1: unmarshaling agent message payload: unexpected end of JSON input",
+		},
+		{
+			name: "other message",
+			line: debugPrefix + "Acquiring lock: lock:deployment:concourse",
+		},
+	})
+}
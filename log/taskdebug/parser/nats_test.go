@@ -0,0 +1,49 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug"
+)
+
+func TestNATSMessageParser(t *testing.T) {
+	runParserTests(t, NATSMessageParser, []parserTest{
+		{
+			name: "sent",
+			line: debugPrefix + `SENT: hm.director.alert {"id":"abc"}`,
+			expected: func(raw taskdebug.RawMessage) log.Line {
+				return taskdebug.NATSMessageMessage{
+					RawMessage: raw,
+					Event:      "SENT",
+					Channel:    "hm.director.alert",
+					Payload:    `{"id":"abc"}`,
+				}
+			},
+		},
+		{
+			name: "received",
+			line: debugPrefix + `RECEIVED: director.abc.1 {"value":{"agent_task_id":"task-1","state":"running"}}`,
+			expected: func(raw taskdebug.RawMessage) log.Line {
+				return taskdebug.NATSMessageMessage{
+					RawMessage: raw,
+					Event:      "RECEIVED",
+					Channel:    "director.abc.1",
+					Payload:    `{"value":{"agent_task_id":"task-1","state":"running"}}`,
+				}
+			},
+		},
+		{
+			name: "without payload",
+			line: debugPrefix + "SENT: hm.director.alert",
+		},
+		{
+			name: "without channel",
+			line: debugPrefix + `SENT:  {"id":"abc"}`,
+		},
+		{
+			name: "other message",
+			line: debugPrefix + "Acquiring lock: lock:deployment:concourse",
+		},
+	})
+}
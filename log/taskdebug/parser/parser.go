@@ -1,20 +1,193 @@
 package parser
 
-import "github.com/dpb587/bosh-log-tracer/log"
+import (
+	"regexp"
+	"time"
 
-var Parser = log.NewMultiParser(
-	RawParser,
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug"
+	eventparser "github.com/dpb587/bosh-log-tracer/log/taskevent/parser"
+)
 
-	ProcessParser,
-	SequelParser,
-	LockParser,
-	InstanceAspectChangedParser,
+// Options configure how a task log is parsed.
+type Options struct {
+	// Location is assumed for timestamps which do not include a zone offset;
+	// the default is UTC.
+	Location *time.Location
+}
 
-	NATSMessageSentAgentParser,
-	NATSMessageParser,
+// newParser builds the stages of parsing a line, with wrap applied to each
+// individual parser.
+func (o Options) newParser(wrap func(log.LineParser) log.LineParser) debugParser {
+	location := o.Location
+	if location == nil {
+		location = time.UTC
+	}
 
-	ExternalCPIRequestParser,
-	ExternalCPIParser,
+	return debugParser{
+		raw:      wrap(NewRawParser(location)),
+		event:    wrap(eventparser.Parser),
+		inferrer: &timeInferrer{},
+		registry: newRegistry(wrap),
+	}
+}
 
-	CPIAWSRPCParser,
-)
+// newRegistry registers the parsers of specific messages.
+func newRegistry(wrap func(log.LineParser) log.LineParser) *Registry {
+	registry := NewRegistry()
+
+	registry.Register("DirectorJobRunner", "Running from worker ", wrap(ProcessParser))
+	registry.Register("DirectorJobRunner", "Found task", wrap(TaskParser))
+	registry.Register("DirectorJobRunner", "Performing task", wrap(TaskParser))
+	registry.Register("", "Task ", wrap(TaskStateParser))
+	registry.Register("", "task ", wrap(TaskStateParser))
+	registry.Register("", "", wrap(ErrorParser))
+	registry.Register("DirectorJobRunner", "(", wrap(SequelParser))
+
+	for _, prefix := range []string{"Acquiring lock: ", "Acquired lock: ", "Renewing lock: ", "Deleted lock: "} {
+		registry.Register("DirectorJobRunner", prefix, wrap(LockParser))
+	}
+
+	// the message starts with the aspect (e.g. stemcell_changed?)
+	registry.Register("DirectorJobRunner", "", wrap(InstanceAspectChangedParser))
+
+	for _, prefix := range []string{"Downloading remote ", "Extracting ", "Verifying ", "Checking if this ", "Uploading ", "Saving "} {
+		registry.Register("DirectorJobRunner", prefix, wrap(ArtifactStepParser))
+	}
+
+	registry.Register("DirectorJobRunner", "Creating ", wrap(ReleasePackageParser))
+	registry.Register("DirectorJobRunner", "[blobstore] ", wrap(BlobstoreParser))
+
+	registry.Register("DirectorJobRunner", "SENT: ", wrap(NATSMessageParser), wrap(NATSMessageSentAgentParser))
+	registry.Register("DirectorJobRunner", "RECEIVED: ", wrap(NATSMessageParser), wrap(NATSMessageSentAgentParser))
+
+	registry.Register("DirectorJobRunner", "[external-cpi] ", wrap(ExternalCPIParser), wrap(ExternalCPIRequestParser), wrap(ExternalCPIResponseParser))
+
+	registry.Register("ExternalCpiLog", "[Aws::EC2::Client ", wrap(CPIAWSRPCParser))
+	registry.Register("ExternalCpiLog", "", wrap(CPILogParser), wrap(CPIWaitParser))
+
+	return registry
+}
+
+// NewParser returns a parser for a single task log. Parsers must not be shared
+// between logs since the time of lines without a timestamp is inferred from the
+// lines around them.
+func NewParser(options Options) log.LineParser {
+	return options.newParser(noWrap)
+}
+
+// NewLenientParser returns a parser which records the errors of individual
+// parsers as diagnostics rather than failing on the line.
+func NewLenientParser(options Options, diagnostics *log.Diagnostics) log.LineParser {
+	return options.newParser(func(p log.LineParser) log.LineParser {
+		return log.NewLenientParser(p, diagnostics)
+	})
+}
+
+func noWrap(p log.LineParser) log.LineParser {
+	return p
+}
+
+// debugParser parses the lines of a task debug log, along with the lines of its
+// event log if they are included. Only lines without a timestamp depend on the
+// lines before them, so every other line is parsed completely by
+// ParseConcurrently.
+type debugParser struct {
+	raw      log.LineParser
+	event    log.LineParser
+	inferrer *timeInferrer
+	registry log.LineParser
+}
+
+var _ log.BufferedParser = debugParser{}
+var _ log.EntryMatcher = debugParser{}
+
+func (p debugParser) Parse(in log.Line) (log.Line, error) {
+	out, err := p.ParseConcurrently(in)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.ParseOrdered(out)
+}
+
+func (p debugParser) ParseConcurrently(in log.Line) (log.Line, error) {
+	if eventparser.IsEvent(in.LineData()) {
+		return p.event.Parse(in)
+	}
+
+	out, err := p.raw.Parse(in)
+	if err != nil {
+		return nil, err
+	}
+
+	if raw, ok := out.(taskdebug.RawMessage); ok && raw.LogTime.IsZero() {
+		// completed once the time of the lines before it is known
+		return out, nil
+	}
+
+	return p.registry.Parse(out)
+}
+
+func (p debugParser) ParseOrdered(in log.Line) (log.Line, error) {
+	if raw, ok := in.(taskdebug.RawMessage); ok && raw.LogTime.IsZero() {
+		out, err := p.inferrer.Parse(raw)
+		if err != nil {
+			return nil, err
+		}
+
+		return p.registry.Parse(out)
+	}
+
+	return p.inferrer.Parse(in)
+}
+
+// ParseBuffered is like ParseOrdered, but lines before the first timestamp are
+// held until it is known rather than left without a time.
+func (p debugParser) ParseBuffered(in log.Line) ([]log.Line, error) {
+	lines := p.inferrer.Backfill(in)
+
+	for idx, l := range lines {
+		var err error
+
+		if raw, ok := l.(taskdebug.RawMessage); ok && raw.LogTimeInferred {
+			// held before its time was known, so not yet parsed further
+			lines[idx], err = p.registry.Parse(raw)
+		} else {
+			lines[idx], err = p.ParseOrdered(l)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return lines, nil
+}
+
+// Drain completes the lines held by ParseBuffered without a time since no
+// timestamp followed them.
+func (p debugParser) Drain() ([]log.Line, error) {
+	lines := p.inferrer.Drain()
+
+	for idx, l := range lines {
+		var err error
+
+		lines[idx], err = p.ParseOrdered(l)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return lines, nil
+}
+
+// I, [2019-06-19T01:44:52.546138 #26587]
+var entryStartRE = regexp.MustCompile(`^\w, \[[^ ]+ #\d+\]`)
+
+// IsEntryStart lets the pipeline reassemble entries which span several lines
+// (e.g. backtraces) before they are parsed.
+func (p debugParser) IsEntryStart(data string) bool {
+	// the task state is written without a logger header
+	return entryStartRE.MatchString(data) || taskStateOneRE.MatchString(data) || eventparser.IsEvent(data)
+}
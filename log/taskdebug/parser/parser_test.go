@@ -0,0 +1,183 @@
+package parser
+
+import (
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/input"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug"
+	"github.com/dpb587/bosh-log-tracer/log/taskevent"
+	"github.com/dpb587/bosh-log-tracer/pipeline"
+)
+
+const (
+	taskPrefix  = "I, [2019-06-19T01:44:52.546200 #26587] [task:80528]  INFO -- DirectorJobRunner: "
+	debugPrefix = "D, [2019-06-19T01:44:52.549000 #26587] [task:80528] DEBUG -- DirectorJobRunner: "
+)
+
+type parserTest struct {
+	name string
+	line string
+
+	// expected builds the message from the line's raw message; nil expects the
+	// raw message to be returned unchanged.
+	expected func(raw taskdebug.RawMessage) log.Line
+
+	expectedErr string
+}
+
+func newRawLine(data string) log.RawLine {
+	return log.RawLine{
+		RawLineSource: "test.log",
+		RawLineOffset: 1,
+		RawLineData:   data,
+	}
+}
+
+// parseRaw parses data the way every other parser expects to receive it.
+func parseRaw(t *testing.T, data string) taskdebug.RawMessage {
+	t.Helper()
+
+	out, err := RawParser.Parse(newRawLine(data))
+	if err != nil {
+		t.Fatalf("parsing raw message: %s", err)
+	}
+
+	return out.(taskdebug.RawMessage)
+}
+
+func runParserTests(t *testing.T, p log.LineParser, tests []parserTest) {
+	t.Helper()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := parseRaw(t, tt.line)
+
+			actual, err := p.Parse(raw)
+			if tt.expectedErr != "" {
+				if err == nil {
+					t.Fatalf("expected error %q, but got none", tt.expectedErr)
+				} else if err.Error() != tt.expectedErr {
+					t.Fatalf("expected error %q, but got %q", tt.expectedErr, err.Error())
+				}
+
+				return
+			} else if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			var expected log.Line = raw
+			if tt.expected != nil {
+				expected = tt.expected(raw)
+			}
+
+			if !reflect.DeepEqual(actual, expected) {
+				t.Errorf("expected:\n%#+v\nactual:\n%#+v", expected, actual)
+			}
+		})
+	}
+}
+
+func TestParserFixtures(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("..", "testdata", "*.log"))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(paths) == 0 {
+		t.Fatal("expected fixtures")
+	}
+
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			var typed int
+
+			p := NewParser(Options{})
+
+			err := input.NewFileInput(path).Scan(func(l log.Line) error {
+				out, err := p.Parse(l)
+				if err != nil {
+					return err
+				}
+
+				if _, ok := out.(taskdebug.RawMessage); !ok {
+					typed++
+				}
+
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if typed == 0 {
+				t.Errorf("expected some lines to be parsed into specific messages")
+			}
+		})
+	}
+}
+
+func TestParserIsEntryStart(t *testing.T) {
+	tests := []struct {
+		data     string
+		expected bool
+	}{
+		{data: taskPrefix + "Starting task: 80528", expected: true},
+		{data: `I, [2019-06-19T01:47:50.061354 #26935]  INFO -- [req_id cpi-354031]: Starting create_vm...`, expected: true},
+		{data: "Task 80528 error", expected: true},
+		{data: "/var/vcap/packages/director/lib/bosh/director/instance_updater.rb:87:in `update'", expected: false},
+		{data: `  FROM "tasks"`, expected: false},
+		{data: "", expected: false},
+	}
+
+	matcher, ok := NewParser(Options{}).(log.EntryMatcher)
+	if !ok {
+		t.Fatal("expected the parser to reassemble entries")
+	}
+
+	for _, tt := range tests {
+		if actual := matcher.IsEntryStart(tt.data); actual != tt.expected {
+			t.Errorf("expected %q to be an entry start: %v", tt.data, tt.expected)
+		}
+	}
+}
+
+func TestParserTaskDirectory(t *testing.T) {
+	// the CPI log of a task may begin with output which has no header
+	cpi := input.NewInput("cpi", func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(strings.NewReader(strings.Join([]string{
+			"/var/vcap/packages/bosh_aws_cpi/lib/cloud/aws/cloud.rb:120: warning: already initialized constant",
+			`I, [2019-06-19T01:44:53.724100 #26935]  INFO -- [req_id cpi-308951]: Starting create_vm...`,
+		}, "\n"))), nil
+	})
+
+	in := input.Concat(
+		input.NewFileInput(filepath.Join("..", "testdata", "deploy-compilation.event")),
+		cpi,
+		input.NewFileInput(filepath.Join("..", "testdata", "deploy-compilation.log")),
+	)
+
+	for _, workers := range []int{1, 4} {
+		obs := &recordingObserver{}
+
+		err := pipeline.RunConcurrently(in, NewParser(Options{}), obs, workers)
+		if err != nil {
+			t.Fatalf("%d workers: unexpected error: %s", workers, err)
+		}
+
+		var events int
+
+		for _, l := range obs.lines {
+			if _, ok := l.(taskevent.EventMessage); ok {
+				events++
+			}
+		}
+
+		if events == 0 {
+			t.Errorf("%d workers: expected events to be parsed", workers)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug"
+)
+
+func TestProcessParser(t *testing.T) {
+	runParserTests(t, ProcessParser, []parserTest{
+		{
+			name: "running from worker",
+			line: "I, [2019-06-19T01:44:52.546138 #26587] []  INFO -- DirectorJobRunner: Running from worker 'worker_4' on director/e522142e-d0e2-4605-7c57-2cab3e749003 (127.0.0.1)",
+			expected: func(raw taskdebug.RawMessage) log.Line {
+				return taskdebug.ProcessMessage{
+					RawMessage:   raw,
+					WorkerName:   "worker_4",
+					InstanceName: "director",
+					InstanceID:   "e522142e-d0e2-4605-7c57-2cab3e749003",
+					IP:           "127.0.0.1",
+				}
+			},
+		},
+		{
+			name: "other message",
+			line: taskPrefix + "Starting task: 80528",
+		},
+	})
+}
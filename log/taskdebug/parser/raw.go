@@ -3,15 +3,26 @@ package parser
 import (
 	"fmt"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/dpb587/bosh-log-tracer/log"
 	"github.com/dpb587/bosh-log-tracer/log/taskdebug"
 )
 
-var RawParser = rawParser{}
+var RawParser = NewRawParser(time.UTC)
 
-type rawParser struct{}
+type rawParser struct {
+	location *time.Location
+}
+
+// NewRawParser returns a parser which assumes location for timestamps without a
+// zone offset.
+func NewRawParser(location *time.Location) log.LineParser {
+	return rawParser{
+		location: location,
+	}
+}
 
 // I, [2019-06-19T01:44:52.546138 #26587] []  INFO -- DirectorJobRunner: ...
 var rawOneRE = regexp.MustCompile(`^(\w), \[([^ ]+) #(\d+)\] \[([^\]]*)\]\s+(\w+) -- ([^:]+): (.+)$`)
@@ -30,6 +41,11 @@ func (p rawParser) Parse(inU log.Line) (log.Line, error) {
 		Message: in.RawLineData,
 	}
 
+	if lines := strings.Split(in.RawLineData, "\n"); len(lines) > 1 {
+		out.Message = lines[0]
+		out.Continuation = lines[1:]
+	}
+
 	if m := rawOneRE.FindStringSubmatch(out.Message); len(m) > 0 {
 		out.Process = m[3]
 		out.Tags = p.parseTags(m[4])
@@ -37,9 +53,12 @@ func (p rawParser) Parse(inU log.Line) (log.Line, error) {
 		out.Component = m[6]
 		out.Message = m[7]
 
-		if t, err := time.Parse("2006-01-02T15:04:05", m[2]); err == nil {
-			out.LogTime = t
+		t, err := parseLogTime(m[2], p.location)
+		if err != nil {
+			return nil, fmt.Errorf("parsing log time: %s", err)
 		}
+
+		out.LogTime = t
 	} else if m := rawTwoRE.FindStringSubmatch(out.Message); len(m) > 0 {
 		out.Process = m[3]
 		out.Tags = map[string]string{"req_id": "cpi-" + m[5]}
@@ -47,9 +66,12 @@ func (p rawParser) Parse(inU log.Line) (log.Line, error) {
 		out.Component = "ExternalCpiLog"
 		out.Message = m[6]
 
-		if t, err := time.Parse("2006-01-02T15:04:05", m[2]); err == nil {
-			out.LogTime = t
+		t, err := parseLogTime(m[2], p.location)
+		if err != nil {
+			return nil, fmt.Errorf("parsing log time: %s", err)
 		}
+
+		out.LogTime = t
 	}
 
 	return out, nil
@@ -0,0 +1,163 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug"
+)
+
+func TestRawParser(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		expected taskdebug.RawMessage
+	}{
+		{
+			name: "task",
+			line: "I, [2019-06-19T01:44:52.546200 #26587] [task:80528]  INFO -- DirectorJobRunner: Starting task: 80528",
+			expected: taskdebug.RawMessage{
+				LogTime:   time.Date(2019, 6, 19, 1, 44, 52, 546200000, time.UTC),
+				LogLevel:  "INFO",
+				Process:   "26587",
+				Tags:      map[string]string{"task": "80528"},
+				Component: "DirectorJobRunner",
+				Message:   "Starting task: 80528",
+			},
+		},
+		{
+			name: "no tags",
+			line: "I, [2019-06-19T01:44:52.546138 #26587] []  INFO -- DirectorJobRunner: Running from worker 'worker_4' on director/e522142e-d0e2-4605-7c57-2cab3e749003 (127.0.0.1)",
+			expected: taskdebug.RawMessage{
+				LogTime:   time.Date(2019, 6, 19, 1, 44, 52, 546138000, time.UTC),
+				LogLevel:  "INFO",
+				Process:   "26587",
+				Tags:      map[string]string{},
+				Component: "DirectorJobRunner",
+				Message:   "Running from worker 'worker_4' on director/e522142e-d0e2-4605-7c57-2cab3e749003 (127.0.0.1)",
+			},
+		},
+		{
+			name: "compile_package",
+			line: "I, [2019-06-19T01:44:53.700000 #26587] [compile_package(golang/e8d0a259ffde97201489d0b5f47822026cdfebf1, ubuntu-xenial/315.41)]  INFO -- DirectorJobRunner: Compiling package 'golang/1.12'",
+			expected: taskdebug.RawMessage{
+				LogTime:  time.Date(2019, 6, 19, 1, 44, 53, 700000000, time.UTC),
+				LogLevel: "INFO",
+				Process:  "26587",
+				Tags: map[string]string{
+					"action":              "compile_package",
+					"package":             "golang/e8d0a259ffde97201489d0b5f47822026cdfebf1",
+					"package_name":        "golang",
+					"package_fingerprint": "e8d0a259ffde97201489d0b5f47822026cdfebf1",
+					"stemcell":            "ubuntu-xenial/315.41",
+					"stemcell_os":         "ubuntu-xenial",
+					"stemcell_version":    "315.41",
+				},
+				Component: "DirectorJobRunner",
+				Message:   "Compiling package 'golang/1.12'",
+			},
+		},
+		{
+			name: "create_missing_vm",
+			line: "I, [2019-06-19T01:44:58.200000 #26587] [create_missing_vm(compilation-1b6dfd75-028e-469c-9512-bcce3b0a5504/6056c8c0-2bad-40cf-bcec-4c56f66f12de (0)/1)]  INFO -- DirectorJobRunner: Creating missing VM",
+			expected: taskdebug.RawMessage{
+				LogTime:  time.Date(2019, 6, 19, 1, 44, 58, 200000000, time.UTC),
+				LogLevel: "INFO",
+				Process:  "26587",
+				Tags: map[string]string{
+					"action":         "create_missing_vm",
+					"instance_group": "compilation-1b6dfd75-028e-469c-9512-bcce3b0a5504",
+					"instance_id":    "6056c8c0-2bad-40cf-bcec-4c56f66f12de",
+					"instance_index": "0",
+				},
+				Component: "DirectorJobRunner",
+				Message:   "Creating missing VM",
+			},
+		},
+		{
+			name: "canary_update",
+			line: "D, [2019-06-19T01:44:58.300000 #26587] [canary_update(web/6318b9e7-8c72-4c4e-8769-e59abaa32297 (0))] DEBUG -- DirectorJobRunner: Updating instance web/6318b9e7-8c72-4c4e-8769-e59abaa32297 (0) (canary)",
+			expected: taskdebug.RawMessage{
+				LogTime:  time.Date(2019, 6, 19, 1, 44, 58, 300000000, time.UTC),
+				LogLevel: "DEBUG",
+				Process:  "26587",
+				Tags: map[string]string{
+					"action":         "canary_update",
+					"instance_group": "web",
+					"instance_id":    "6318b9e7-8c72-4c4e-8769-e59abaa32297",
+					"instance_index": "0",
+				},
+				Component: "DirectorJobRunner",
+				Message:   "Updating instance web/6318b9e7-8c72-4c4e-8769-e59abaa32297 (0) (canary)",
+			},
+		},
+		{
+			name: "external cpi",
+			line: `I, [2019-06-19T01:44:54.000000 #26935]  INFO -- [req_id cpi-308955]: [Aws::EC2::Client 200 1.069542 0 retries] run_instances(image_id:"ami-1")`,
+			expected: taskdebug.RawMessage{
+				LogTime:   time.Date(2019, 6, 19, 1, 44, 54, 0, time.UTC),
+				LogLevel:  "INFO",
+				Process:   "26935",
+				Tags:      map[string]string{"req_id": "cpi-308955"},
+				Component: "ExternalCpiLog",
+				Message:   `[Aws::EC2::Client 200 1.069542 0 retries] run_instances(image_id:"ami-1")`,
+			},
+		},
+		{
+			name: "multi-line",
+			line: "E, [2019-06-19T01:45:09.100000 #26587] [task:80528] ERROR -- DirectorJobRunner: Bosh::Director::TaskCancelled\n/var/vcap/packages/director/lib/bosh/director/jobs/base_job.rb:42:in `task_checkpoint'\n/var/vcap/packages/director/lib/bosh/director/jobs/update_deployment.rb:84:in `perform'",
+			expected: taskdebug.RawMessage{
+				LogTime:   time.Date(2019, 6, 19, 1, 45, 9, 100000000, time.UTC),
+				LogLevel:  "ERROR",
+				Process:   "26587",
+				Tags:      map[string]string{"task": "80528"},
+				Component: "DirectorJobRunner",
+				Message:   "Bosh::Director::TaskCancelled",
+				Continuation: []string{
+					"/var/vcap/packages/director/lib/bosh/director/jobs/base_job.rb:42:in `task_checkpoint'",
+					"/var/vcap/packages/director/lib/bosh/director/jobs/update_deployment.rb:84:in `perform'",
+				},
+			},
+		},
+		{
+			name: "continuation",
+			line: "/var/vcap/packages/director/gem_home/ruby/2.4.0/gems/bosh-director-0.0.0/lib/bosh/director/instance_updater.rb:87:in `update'",
+			expected: taskdebug.RawMessage{
+				Message: "/var/vcap/packages/director/gem_home/ruby/2.4.0/gems/bosh-director-0.0.0/lib/bosh/director/instance_updater.rb:87:in `update'",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := newRawLine(tt.line)
+
+			actual, err := RawParser.Parse(in)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			expected := tt.expected
+			expected.RawLine = in
+
+			if !reflect.DeepEqual(actual, expected) {
+				t.Errorf("expected:\n%#+v\nactual:\n%#+v", expected, actual)
+			}
+		})
+	}
+}
+
+func TestRawParserIgnoresParsedLines(t *testing.T) {
+	in := parseRaw(t, taskPrefix+"Starting task: 80528")
+
+	actual, err := RawParser.Parse(in)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !reflect.DeepEqual(actual, log.Line(in)) {
+		t.Errorf("expected the message to be unchanged")
+	}
+}
@@ -0,0 +1,99 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug"
+)
+
+// Registry dispatches raw messages to the parsers registered for their
+// component and message prefix, so parsers are only tried on the lines they
+// may understand and at most once per line.
+type Registry struct {
+	// byComponent includes the routes of any component, keeping the order in
+	// which routes were registered.
+	byComponent  map[string][]route
+	anyComponent []route
+}
+
+type route struct {
+	prefix  string
+	parsers []log.LineParser
+}
+
+var _ log.LineParser = &Registry{}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		byComponent: map[string][]route{},
+	}
+}
+
+// Register adds parsers for messages of component which start with prefix; an
+// empty component or prefix matches any message. Each parser receives the
+// result of the one before it, so later parsers may refine the messages of
+// earlier ones. Routes are tried in the order they were registered until one
+// results in a more specific message.
+//
+// Routes with an empty prefix are tried on every message of their component,
+// so their parsers should reject unrelated messages with a cheap check (e.g. a
+// substring) before matching any expression.
+func (r *Registry) Register(component, prefix string, parsers ...log.LineParser) {
+	rt := route{
+		prefix:  prefix,
+		parsers: parsers,
+	}
+
+	if component == "" {
+		r.anyComponent = append(r.anyComponent, rt)
+
+		for c, routes := range r.byComponent {
+			r.byComponent[c] = append(routes, rt)
+		}
+
+		return
+	}
+
+	routes, found := r.byComponent[component]
+	if !found {
+		routes = append([]route(nil), r.anyComponent...)
+	}
+
+	r.byComponent[component] = append(routes, rt)
+}
+
+func (r *Registry) Parse(inU log.Line) (log.Line, error) {
+	in, ok := inU.(taskdebug.RawMessage)
+	if !ok {
+		return inU, nil
+	}
+
+	routes, found := r.byComponent[in.Component]
+	if !found {
+		routes = r.anyComponent
+	}
+
+	for _, rt := range routes {
+		if !strings.HasPrefix(in.Message, rt.prefix) {
+			continue
+		}
+
+		out := inU
+
+		for _, p := range rt.parsers {
+			var err error
+
+			out, err = p.Parse(out)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if _, raw := out.(taskdebug.RawMessage); !raw {
+			return out, nil
+		}
+	}
+
+	return inU, nil
+}
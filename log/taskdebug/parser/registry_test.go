@@ -0,0 +1,188 @@
+package parser
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/input"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug"
+)
+
+// chainParser tries every parser on every message, the way messages were
+// parsed before they were dispatched by a registry.
+var chainParser = log.NewMultiParser(
+	ProcessParser,
+	TaskParser,
+	TaskStateParser,
+	ErrorParser,
+	SequelParser,
+	LockParser,
+	InstanceAspectChangedParser,
+	ArtifactStepParser,
+	ReleasePackageParser,
+	BlobstoreParser,
+
+	NATSMessageSentAgentParser,
+	NATSMessageParser,
+
+	ExternalCPIRequestParser,
+	ExternalCPIResponseParser,
+	ExternalCPIParser,
+
+	CPIAWSRPCParser,
+	CPIWaitParser,
+	CPILogParser,
+)
+
+var registryParser = newRegistry(noWrap)
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("", "Task ", TaskStateParser)
+	registry.Register("DirectorJobRunner", "SENT: ", NATSMessageParser, NATSMessageSentAgentParser)
+	registry.Register("", "", ErrorParser)
+
+	tests := []struct {
+		name     string
+		line     string
+		expected func(raw taskdebug.RawMessage) log.Line
+	}{
+		{
+			name: "any component",
+			line: "Task 80528 done",
+			expected: func(raw taskdebug.RawMessage) log.Line {
+				return taskdebug.TaskStateMessage{RawMessage: raw, TaskID: "80528", State: "done"}
+			},
+		},
+		{
+			name: "refined",
+			line: debugPrefix + `SENT: agent.0e2a1093-0ace-4685-a361-a6f40a11f7ed {"protocol":3,"method":"ping","arguments":[],"reply_to":"director.abc.2"}`,
+			expected: func(raw taskdebug.RawMessage) log.Line {
+				return taskdebug.NATSMessageSentAgentMessage{
+					NATSMessageMessage: taskdebug.NATSMessageMessage{
+						RawMessage: raw,
+						Event:      "SENT",
+						Channel:    "agent.0e2a1093-0ace-4685-a361-a6f40a11f7ed",
+						Payload:    `{"protocol":3,"method":"ping","arguments":[],"reply_to":"director.abc.2"}`,
+					},
+					AgentID:         "0e2a1093-0ace-4685-a361-a6f40a11f7ed",
+					PayloadProtocol: 3,
+					PayloadMethod:   "ping",
+					PayloadReplyTo:  "director.abc.2",
+				}
+			},
+		},
+		{
+			name: "other component",
+			line: `I, [2019-06-19T01:47:50.061354 #26935]  INFO -- [req_id cpi-354031]: SENT: agent.0e2a1093-0ace-4685-a361-a6f40a11f7ed {}`,
+		},
+		{
+			name: "later route",
+			line: "E, [2019-06-19T01:45:09.100000 #26587] [task:80528] ERROR -- DirectorJobRunner: Task 80528 could not be found",
+			expected: func(raw taskdebug.RawMessage) log.Line {
+				return taskdebug.ErrorMessage{RawMessage: raw, ErrorMessage: raw.Message}
+			},
+		},
+		{
+			name: "unknown",
+			line: taskPrefix + "Starting task: 80528",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := parseRaw(t, tt.line)
+
+			actual, err := registry.Parse(raw)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			var expected log.Line = raw
+			if tt.expected != nil {
+				expected = tt.expected(raw)
+			}
+
+			if !reflect.DeepEqual(actual, expected) {
+				t.Errorf("expected:\n%#+v\nactual:\n%#+v", expected, actual)
+			}
+		})
+	}
+}
+
+func TestRegistryMatchesChain(t *testing.T) {
+	for _, l := range fixtureMessages(t) {
+		expected, expectedErr := chainParser.Parse(l)
+		actual, actualErr := registryParser.Parse(l)
+
+		if !reflect.DeepEqual(actualErr, expectedErr) {
+			t.Errorf("%s:%d: expected error %v, but got %v", l.LineSource(), l.LineOffset(), expectedErr, actualErr)
+		} else if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("%s:%d: expected:\n%#+v\nactual:\n%#+v", l.LineSource(), l.LineOffset(), expected, actual)
+		}
+	}
+}
+
+func BenchmarkChainParser(b *testing.B) {
+	benchmarkParser(b, chainParser)
+}
+
+func BenchmarkRegistryParser(b *testing.B) {
+	benchmarkParser(b, registryParser)
+}
+
+// benchmarkParser measures the parsing of fixture messages once the raw parser
+// has handled them, since that is the same regardless of dispatching.
+func benchmarkParser(b *testing.B, p log.LineParser) {
+	lines := fixtureMessages(b)
+
+	var size int64
+
+	for _, l := range lines {
+		size += int64(len(l.LineData()) + 1)
+	}
+
+	b.SetBytes(size)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for _, l := range lines {
+			_, err := p.Parse(l)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func fixtureMessages(tb testing.TB) []log.Line {
+	tb.Helper()
+
+	paths, err := filepath.Glob(filepath.Join("..", "testdata", "*.log"))
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	var lines []log.Line
+
+	for _, path := range paths {
+		err := input.NewFileInput(path).Scan(func(l log.Line) error {
+			l, err := RawParser.Parse(l)
+			if err != nil {
+				return err
+			}
+
+			lines = append(lines, l)
+
+			return nil
+		})
+		if err != nil {
+			tb.Fatal(err)
+		}
+	}
+
+	return lines
+}
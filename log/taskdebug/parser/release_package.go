@@ -0,0 +1,41 @@
+package parser
+
+import (
+	"regexp"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug"
+)
+
+var ReleasePackageParser = releasePackageParser{}
+
+type releasePackageParser struct{}
+
+// Creating new package 'golang-1.12-linux/8a1f2a8c0a3d4b5e9d3c2e4b1f7a6c5d8e9f0a1b'
+// Creating compiled package 'golang-1.12-linux/8a1f2a8c0a3d4b5e9d3c2e4b1f7a6c5d8e9f0a1b' for stemcell 'ubuntu-xenial/315.41'
+var releasePackageOneRE = regexp.MustCompile(`^Creating (new|compiled) package '([^/']+)/([^']+)'(?: for stemcell '([^']+)')?$`)
+
+func (p releasePackageParser) Parse(inU log.Line) (log.Line, error) {
+	in, ok := inU.(taskdebug.RawMessage)
+	if !ok {
+		return inU, nil
+	}
+
+	if in.Component != "DirectorJobRunner" {
+		return inU, nil
+	}
+
+	if m := releasePackageOneRE.FindStringSubmatch(in.Message); len(m) > 0 {
+		out := taskdebug.ReleasePackageMessage{
+			RawMessage:     in,
+			Event:          m[1],
+			PackageName:    m[2],
+			PackageVersion: m[3],
+			Stemcell:       m[4],
+		}
+
+		return out, nil
+	}
+
+	return inU, nil
+}
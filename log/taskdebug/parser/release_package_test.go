@@ -0,0 +1,42 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug"
+)
+
+func TestReleasePackageParser(t *testing.T) {
+	runParserTests(t, ReleasePackageParser, []parserTest{
+		{
+			name: "new",
+			line: taskPrefix + "Creating new package 'golang-1.12-linux/8a1f2a8c0a3d4b5e9d3c2e4b1f7a6c5d8e9f0a1b'",
+			expected: func(raw taskdebug.RawMessage) log.Line {
+				return taskdebug.ReleasePackageMessage{
+					RawMessage:     raw,
+					Event:          "new",
+					PackageName:    "golang-1.12-linux",
+					PackageVersion: "8a1f2a8c0a3d4b5e9d3c2e4b1f7a6c5d8e9f0a1b",
+				}
+			},
+		},
+		{
+			name: "compiled",
+			line: taskPrefix + "Creating compiled package 'golang-1.12-linux/8a1f2a8c0a3d4b5e9d3c2e4b1f7a6c5d8e9f0a1b' for stemcell 'ubuntu-xenial/315.41'",
+			expected: func(raw taskdebug.RawMessage) log.Line {
+				return taskdebug.ReleasePackageMessage{
+					RawMessage:     raw,
+					Event:          "compiled",
+					PackageName:    "golang-1.12-linux",
+					PackageVersion: "8a1f2a8c0a3d4b5e9d3c2e4b1f7a6c5d8e9f0a1b",
+					Stemcell:       "ubuntu-xenial/315.41",
+				}
+			},
+		},
+		{
+			name: "other message",
+			line: taskPrefix + "Compiling package 'golang/1.12'",
+		},
+	})
+}
@@ -3,6 +3,7 @@ package parser
 import (
 	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/dpb587/bosh-log-tracer/log"
@@ -33,8 +34,12 @@ func (p sequelParser) Parse(inU log.Line) (log.Line, error) {
 			Query:      m[3],
 		}
 
+		if len(in.Continuation) > 0 {
+			msg.Query = strings.Join(append([]string{msg.Query}, in.Continuation...), "\n")
+		}
+
 		if res, err := strconv.ParseFloat(m[1], 64); err == nil {
-			msg.Duration = time.Duration(int64(res * 1000000))
+			msg.Duration = time.Duration(int64(res * float64(time.Second)))
 		}
 
 		return msg, nil
@@ -0,0 +1,54 @@
+package parser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug"
+)
+
+func TestSequelParser(t *testing.T) {
+	runParserTests(t, SequelParser, []parserTest{
+		{
+			name: "select",
+			line: debugPrefix + `(0.000175s) (conn: 47432699065800) SELECT * FROM "tasks" WHERE "id" = 80528`,
+			expected: func(raw taskdebug.RawMessage) log.Line {
+				return taskdebug.SequelMessage{
+					RawMessage: raw,
+					Duration:   175 * time.Microsecond,
+					Connection: "47432699065800",
+					Query:      `SELECT * FROM "tasks" WHERE "id" = 80528`,
+				}
+			},
+		},
+		{
+			name: "slow",
+			line: debugPrefix + `(1.500000s) (conn: 47432699065800) COMMIT`,
+			expected: func(raw taskdebug.RawMessage) log.Line {
+				return taskdebug.SequelMessage{
+					RawMessage: raw,
+					Duration:   1500 * time.Millisecond,
+					Connection: "47432699065800",
+					Query:      "COMMIT",
+				}
+			},
+		},
+		{
+			name: "multi-line",
+			line: debugPrefix + "(0.000412s) (conn: 47432699065800) SELECT *\nFROM \"tasks\"\nWHERE \"id\" = 80528",
+			expected: func(raw taskdebug.RawMessage) log.Line {
+				return taskdebug.SequelMessage{
+					RawMessage: raw,
+					Duration:   412 * time.Microsecond,
+					Connection: "47432699065800",
+					Query:      "SELECT *\nFROM \"tasks\"\nWHERE \"id\" = 80528",
+				}
+			},
+		},
+		{
+			name: "other message",
+			line: debugPrefix + "Acquiring lock: lock:deployment:concourse",
+		},
+	})
+}
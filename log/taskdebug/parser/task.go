@@ -0,0 +1,59 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug"
+)
+
+var TaskParser = taskParser{}
+
+type taskParser struct{}
+
+// Performing task: #<Bosh::Director::Models::Task @values={:id=>80528, :state=>"processing", ..., :type=>"update_deployment", ...}>
+var taskOneRE = regexp.MustCompile(`^(Found|Performing) task:? #<Bosh::Director::Models::Task @values=\{(.+)\}>$`)
+
+// :description=>"create deployment"
+// :id=>80528
+var taskValueRE = regexp.MustCompile(`:(\w+)=>("(?:[^"\\]|\\.)*"|[^,]+)`)
+
+func (p taskParser) Parse(inU log.Line) (log.Line, error) {
+	in, ok := inU.(taskdebug.RawMessage)
+	if !ok {
+		return inU, nil
+	}
+
+	if in.Component != "DirectorJobRunner" {
+		return inU, nil
+	}
+
+	if m := taskOneRE.FindStringSubmatch(in.Message); len(m) > 0 {
+		out := taskdebug.TaskMessage{
+			RawMessage: in,
+			Event:      strings.ToLower(m[1]),
+		}
+
+		for _, v := range taskValueRE.FindAllStringSubmatch(m[2], -1) {
+			value := strings.TrimSuffix(strings.TrimPrefix(v[2], `"`), `"`)
+
+			switch v[1] {
+			case "id":
+				out.TaskID = value
+			case "type":
+				out.Type = value
+			case "description":
+				out.Description = value
+			case "deployment_name":
+				out.DeploymentName = value
+			case "username":
+				out.Username = value
+			}
+		}
+
+		return out, nil
+	}
+
+	return inU, nil
+}
@@ -0,0 +1,36 @@
+package parser
+
+import (
+	"regexp"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug"
+)
+
+var TaskStateParser = taskStateParser{}
+
+type taskStateParser struct{}
+
+// Task 80528 done
+// Task 80528 error
+// Task 80528 cancelled
+var taskStateOneRE = regexp.MustCompile(`^[Tt]ask (\d+) (done|error|cancelled)$`)
+
+func (p taskStateParser) Parse(inU log.Line) (log.Line, error) {
+	in, ok := inU.(taskdebug.RawMessage)
+	if !ok {
+		return inU, nil
+	}
+
+	if m := taskStateOneRE.FindStringSubmatch(in.Message); len(m) > 0 {
+		out := taskdebug.TaskStateMessage{
+			RawMessage: in,
+			TaskID:     m[1],
+			State:      m[2],
+		}
+
+		return out, nil
+	}
+
+	return inU, nil
+}
@@ -0,0 +1,50 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug"
+)
+
+func TestTaskStateParser(t *testing.T) {
+	runParserTests(t, TaskStateParser, []parserTest{
+		{
+			name: "error",
+			line: "Task 80528 error",
+			expected: func(raw taskdebug.RawMessage) log.Line {
+				return taskdebug.TaskStateMessage{
+					RawMessage: raw,
+					TaskID:     "80528",
+					State:      "error",
+				}
+			},
+		},
+		{
+			name: "logged done",
+			line: taskPrefix + "Task 80528 done",
+			expected: func(raw taskdebug.RawMessage) log.Line {
+				return taskdebug.TaskStateMessage{
+					RawMessage: raw,
+					TaskID:     "80528",
+					State:      "done",
+				}
+			},
+		},
+		{
+			name: "cancelled",
+			line: "task 80528 cancelled",
+			expected: func(raw taskdebug.RawMessage) log.Line {
+				return taskdebug.TaskStateMessage{
+					RawMessage: raw,
+					TaskID:     "80528",
+					State:      "cancelled",
+				}
+			},
+		},
+		{
+			name: "other message",
+			line: taskPrefix + "Task took 8.0 seconds to process.",
+		},
+	})
+}
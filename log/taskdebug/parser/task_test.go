@@ -0,0 +1,46 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug"
+)
+
+func TestTaskParser(t *testing.T) {
+	runParserTests(t, TaskParser, []parserTest{
+		{
+			name: "performing task",
+			line: taskPrefix + `Performing task: #<Bosh::Director::Models::Task @values={:id=>80528, :state=>"processing", :timestamp=>2019-06-19 01:44:52 UTC, :description=>"create deployment", :result=>nil, :output=>"/var/vcap/store/director/tasks/80528", :type=>"update_deployment", :username=>"admin", :deployment_name=>"concourse", :context_id=>""}>`,
+			expected: func(raw taskdebug.RawMessage) log.Line {
+				return taskdebug.TaskMessage{
+					RawMessage:     raw,
+					Event:          "performing",
+					TaskID:         "80528",
+					Type:           "update_deployment",
+					Description:    "create deployment",
+					DeploymentName: "concourse",
+					Username:       "admin",
+				}
+			},
+		},
+		{
+			name: "found task",
+			line: taskPrefix + `Found task #<Bosh::Director::Models::Task @values={:id=>80529, :description=>"delete deployment \"concourse\"", :type=>"delete_deployment", :deployment_name=>nil}>`,
+			expected: func(raw taskdebug.RawMessage) log.Line {
+				return taskdebug.TaskMessage{
+					RawMessage:     raw,
+					Event:          "found",
+					TaskID:         "80529",
+					Type:           "delete_deployment",
+					Description:    `delete deployment \"concourse\"`,
+					DeploymentName: "nil",
+				}
+			},
+		},
+		{
+			name: "other message",
+			line: taskPrefix + "Starting task: 80528",
+		},
+	})
+}
@@ -0,0 +1,116 @@
+package parser
+
+import (
+	"time"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug"
+)
+
+// 2019-06-19T01:44:52.546138
+// 2019-06-19T01:44:52.546138+02:00
+// 2019-06-19T01:44:52.546138-0700
+var logTimeZoneLayouts = []string{
+	"2006-01-02T15:04:05.999999999Z07:00",
+	"2006-01-02T15:04:05.999999999Z0700",
+}
+
+const logTimeLayout = "2006-01-02T15:04:05.999999999"
+
+// parseLogTime parses a logger timestamp, keeping its fractional seconds, and
+// assumes location when it does not include a zone offset.
+func parseLogTime(value string, location *time.Location) (time.Time, error) {
+	for _, layout := range logTimeZoneLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.ParseInLocation(logTimeLayout, value, location)
+}
+
+// timeInferrer uses the time of the previous message for messages which do not
+// have a timestamp of their own (e.g. the final task state). It must see every
+// message in the order of their lines.
+type timeInferrer struct {
+	last time.Time
+
+	// lines before the first timestamp, held by Backfill
+	pending []log.Line
+}
+
+var _ log.LineParser = &timeInferrer{}
+
+func (p *timeInferrer) Parse(inU log.Line) (log.Line, error) {
+	if t := logTime(inU); !t.IsZero() {
+		p.last = t
+
+		return inU, nil
+	}
+
+	in, ok := inU.(taskdebug.RawMessage)
+	if !ok || p.last.IsZero() {
+		return inU, nil
+	}
+
+	in.LogTime = p.last
+	in.LogTimeInferred = true
+
+	return in, nil
+}
+
+// Backfill holds messages without a timestamp until the first one with a
+// timestamp, and then returns them with its time followed by that message. Once
+// a timestamp was seen, messages are returned as-is to be given to Parse.
+func (p *timeInferrer) Backfill(in log.Line) []log.Line {
+	if !p.last.IsZero() || len(p.pending) == 0 && !needsLogTime(in) {
+		return []log.Line{in}
+	}
+
+	t := logTime(in)
+	if t.IsZero() {
+		// other lines are held too so the order is kept
+		p.pending = append(p.pending, in)
+
+		return nil
+	}
+
+	res := make([]log.Line, 0, len(p.pending)+1)
+
+	for _, l := range p.pending {
+		if needsLogTime(l) {
+			raw := l.(taskdebug.RawMessage)
+			raw.LogTime = t
+			raw.LogTimeInferred = true
+			l = raw
+		}
+
+		res = append(res, l)
+	}
+
+	p.pending = nil
+
+	return append(res, in)
+}
+
+// Drain returns the held messages when no timestamp followed them.
+func (p *timeInferrer) Drain() []log.Line {
+	res := p.pending
+	p.pending = nil
+
+	return res
+}
+
+func logTime(in log.Line) time.Time {
+	if getter, ok := in.(taskdebug.RawMessageGetter); ok {
+		return getter.GetRawMessage().LogTime
+	}
+
+	return time.Time{}
+}
+
+func needsLogTime(in log.Line) bool {
+	raw, ok := in.(taskdebug.RawMessage)
+
+	return ok && raw.LogTime.IsZero()
+}
@@ -0,0 +1,229 @@
+package parser
+
+import (
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/input"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug"
+	"github.com/dpb587/bosh-log-tracer/pipeline"
+)
+
+func TestParseLogTime(t *testing.T) {
+	denver := time.FixedZone("MDT", -6*60*60)
+
+	tests := []struct {
+		name     string
+		value    string
+		location *time.Location
+		expected time.Time
+	}{
+		{
+			name:     "without zone",
+			value:    "2019-06-19T01:44:52.546200",
+			location: time.UTC,
+			expected: time.Date(2019, 6, 19, 1, 44, 52, 546200000, time.UTC),
+		},
+		{
+			name:     "without zone in location",
+			value:    "2019-06-19T01:44:52.546200",
+			location: denver,
+			expected: time.Date(2019, 6, 19, 7, 44, 52, 546200000, time.UTC),
+		},
+		{
+			name:     "without fractional seconds",
+			value:    "2019-06-19T01:44:52",
+			location: time.UTC,
+			expected: time.Date(2019, 6, 19, 1, 44, 52, 0, time.UTC),
+		},
+		{
+			name:     "with offset",
+			value:    "2019-06-19T01:44:52.546200+02:00",
+			location: denver,
+			expected: time.Date(2019, 6, 18, 23, 44, 52, 546200000, time.UTC),
+		},
+		{
+			name:     "with compact offset",
+			value:    "2019-06-19T01:44:52.546200-0700",
+			location: time.UTC,
+			expected: time.Date(2019, 6, 19, 8, 44, 52, 546200000, time.UTC),
+		},
+		{
+			name:     "with utc designator",
+			value:    "2019-06-19T01:44:52.546200Z",
+			location: denver,
+			expected: time.Date(2019, 6, 19, 1, 44, 52, 546200000, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := parseLogTime(tt.value, tt.location)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if !actual.Equal(tt.expected) {
+				t.Errorf("expected %s, but got %s", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestRawParserInvalidTime(t *testing.T) {
+	_, err := RawParser.Parse(newRawLine("I, [2019-06-19T25:44:52.546200 #26587] [task:80528]  INFO -- DirectorJobRunner: Starting task: 80528"))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestTimeInferrer(t *testing.T) {
+	p := &timeInferrer{}
+
+	first := parseRaw(t, "Task 80528 error")
+
+	actual, err := p.Parse(first)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if !actual.(taskdebug.RawMessage).LogTime.IsZero() {
+		t.Errorf("expected no time without a previous message")
+	}
+
+	_, err = p.Parse(parseRaw(t, taskPrefix+"Starting task: 80528"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	actual, err = p.Parse(parseRaw(t, "Task 80528 error"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	msg := actual.(taskdebug.RawMessage)
+
+	if expected := time.Date(2019, 6, 19, 1, 44, 52, 546200000, time.UTC); !msg.LogTime.Equal(expected) {
+		t.Errorf("expected %s, but got %s", expected, msg.LogTime)
+	} else if !msg.LogTimeInferred {
+		t.Errorf("expected the time to be marked as inferred")
+	}
+}
+
+func TestTimeInferrerBackfill(t *testing.T) {
+	p := &timeInferrer{}
+
+	for _, data := range []string{"Task 80528 error", "  continued"} {
+		if lines := p.Backfill(parseRaw(t, data)); len(lines) != 0 {
+			t.Fatalf("expected %q to be held, but got %d lines", data, len(lines))
+		}
+	}
+
+	lines := p.Backfill(parseRaw(t, taskPrefix+"Starting task: 80528"))
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, but got %d", len(lines))
+	}
+
+	expected := time.Date(2019, 6, 19, 1, 44, 52, 546200000, time.UTC)
+
+	for idx, l := range lines[0:2] {
+		msg := l.(taskdebug.RawMessage)
+
+		if !msg.LogTime.Equal(expected) || !msg.LogTimeInferred {
+			t.Errorf("%d: expected inferred %s, but got %s (inferred %t)", idx, expected, msg.LogTime, msg.LogTimeInferred)
+		}
+	}
+
+	if msg := lines[2].(taskdebug.RawMessage); msg.LogTimeInferred || msg.Message != "Starting task: 80528" {
+		t.Errorf("expected the timestamped line last, but got %#v", msg)
+	}
+
+	_, err := p.Parse(lines[2])
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// only leading lines are held
+	if lines := p.Backfill(parseRaw(t, "Task 80528 done")); len(lines) != 1 {
+		t.Fatalf("expected the line to be returned, but got %d lines", len(lines))
+	} else if lines := p.Drain(); len(lines) != 0 {
+		t.Fatalf("expected nothing to drain, but got %d lines", len(lines))
+	}
+}
+
+func TestTimeInferrerDrain(t *testing.T) {
+	p := &timeInferrer{}
+
+	p.Backfill(parseRaw(t, "Task 80528 error"))
+
+	lines := p.Drain()
+	if len(lines) != 1 {
+		t.Fatalf("expected 1 line, but got %d", len(lines))
+	} else if msg := lines[0].(taskdebug.RawMessage); !msg.LogTime.IsZero() {
+		t.Errorf("expected no time without a timestamp, but got %s", msg.LogTime)
+	}
+}
+
+func TestParserBackfillsLeadingLines(t *testing.T) {
+	data := strings.Join([]string{
+		"  trailing output of a truncated entry",
+		"Task 80527 done",
+		taskPrefix + "Starting task: 80528",
+		"Task 80528 error",
+	}, "\n")
+
+	for _, workers := range []int{1, 4} {
+		obs := &recordingObserver{}
+
+		err := pipeline.RunConcurrently(input.NewInput("test.log", func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(strings.NewReader(data)), nil
+		}), NewParser(Options{}), obs, workers)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if len(obs.lines) != 4 {
+			t.Fatalf("%d workers: expected 4 lines, but got %d", workers, len(obs.lines))
+		}
+
+		expected := time.Date(2019, 6, 19, 1, 44, 52, 546200000, time.UTC)
+
+		for idx, l := range obs.lines {
+			msg := l.(taskdebug.RawMessageGetter).GetRawMessage()
+
+			if msg.LineOffset() != int64(idx+1) {
+				t.Errorf("%d workers: %d: expected line %d, but got %d", workers, idx, idx+1, msg.LineOffset())
+			} else if !msg.LogTime.Equal(expected) {
+				t.Errorf("%d workers: %d: expected %s, but got %s", workers, idx, expected, msg.LogTime)
+			}
+		}
+
+		if state, ok := obs.lines[1].(taskdebug.TaskStateMessage); !ok || state.State != "done" {
+			t.Errorf("%d workers: expected a held line to be parsed further, but got %#v", workers, obs.lines[1])
+		}
+	}
+}
+
+type recordingObserver struct {
+	lines []log.Line
+}
+
+func (o *recordingObserver) Begin() error {
+	return nil
+}
+
+func (o *recordingObserver) Handle(l log.Line) error {
+	o.lines = append(o.lines, l)
+
+	return nil
+}
+
+func (o *recordingObserver) Flush() error {
+	return nil
+}
+
+func (o *recordingObserver) Commit() error {
+	return nil
+}
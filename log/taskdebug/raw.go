@@ -15,6 +15,24 @@ type RawMessage struct {
 	Tags      map[string]string
 	Component string
 	Message   string
+
+	// LogTimeInferred is set when the entry has no timestamp of its own and
+	// LogTime was taken from the entry before it.
+	LogTimeInferred bool
+
+	// Continuation contains any lines following the first one of the entry
+	// (e.g. a backtrace).
+	Continuation []string
 }
 
 var _ log.Line = &RawMessage{}
+
+// GetRawMessage allows the original message to be accessed from any of the
+// more specific message types which embed it.
+func (m RawMessage) GetRawMessage() RawMessage {
+	return m
+}
+
+type RawMessageGetter interface {
+	GetRawMessage() RawMessage
+}
@@ -0,0 +1,16 @@
+package taskdebug
+
+import (
+	"github.com/dpb587/bosh-log-tracer/log"
+)
+
+type ReleasePackageMessage struct {
+	RawMessage
+
+	Event          string
+	PackageName    string
+	PackageVersion string
+	Stemcell       string
+}
+
+var _ log.Line = &ReleasePackageMessage{}
@@ -0,0 +1,18 @@
+package taskdebug
+
+import (
+	"github.com/dpb587/bosh-log-tracer/log"
+)
+
+type TaskMessage struct {
+	RawMessage
+
+	Event          string
+	TaskID         string
+	Type           string
+	Description    string
+	DeploymentName string
+	Username       string
+}
+
+var _ log.Line = &TaskMessage{}
@@ -0,0 +1,14 @@
+package taskdebug
+
+import (
+	"github.com/dpb587/bosh-log-tracer/log"
+)
+
+type TaskStateMessage struct {
+	RawMessage
+
+	TaskID string
+	State  string
+}
+
+var _ log.Line = &TaskStateMessage{}
@@ -0,0 +1,69 @@
+package timeline
+
+import (
+	"fmt"
+
+	"github.com/dpb587/bosh-log-tracer/observer"
+	"github.com/dpb587/bosh-log-tracer/observer/context"
+	"github.com/dpb587/bosh-log-tracer/trace"
+)
+
+// Collection receives the traces of committed tasks, such as a document which
+// combines several of them.
+type Collection interface {
+	AddTrace(name string, tr *trace.Trace)
+}
+
+// Collector adds the timeline of a task to a collection once it has been
+// committed.
+type Collector struct {
+	*Observer
+
+	collection Collection
+	name       string
+}
+
+var _ observer.Observer = &Collector{}
+
+// NewCollector creates an observer for the collection; the trace is named
+// after the task unless a name is given.
+func NewCollector(ctx *context.Context, o ObserverOptions, collection Collection, name string) *Collector {
+	return &Collector{
+		Observer:   NewObserver(ctx, o),
+		collection: collection,
+		name:       name,
+	}
+}
+
+func (l *Collector) Commit() error {
+	err := l.Observer.Commit()
+	if err != nil {
+		return err
+	}
+
+	tr := l.Trace()
+	if len(tr.FinishedSpans()) == 0 {
+		return nil
+	}
+
+	l.collection.AddTrace(l.getName(tr), tr)
+
+	return nil
+}
+
+func (l *Collector) getName(tr *trace.Trace) string {
+	if l.name != "" {
+		return l.name
+	}
+
+	root, found := tr.RootSpan()
+	if !found {
+		return "task"
+	}
+
+	if task, ok := root.GetTag("task"); ok {
+		return fmt.Sprintf("task %v: %s", task, root.OperationName)
+	}
+
+	return root.OperationName
+}
@@ -0,0 +1,174 @@
+package timeline
+
+import (
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug"
+	"github.com/dpb587/bosh-log-tracer/observer/context"
+	"github.com/dpb587/bosh-log-tracer/trace"
+)
+
+// cpiLogCorrelation returns the external CPI request a line of the CPI log was
+// logged during, if it is one.
+func cpiLogCorrelation(msg log.Line) string {
+	switch m := msg.(type) {
+	case taskdebug.CPIAWSRPCMessage:
+		return m.Correlation
+	case taskdebug.CPIWaitMessage:
+		return m.Correlation
+	case taskdebug.CPILogMessage:
+		return m.Correlation
+	}
+
+	return ""
+}
+
+// findCPIRequestSpan returns the span of an external CPI request, if its
+// request was already seen.
+func (l *Observer) findCPIRequestSpan(correlation string) *trace.Span {
+	scope := l.ctx.Find(context.Annotation{Key: "external_cpi.correlation", Value: correlation})
+	if scope == nil {
+		return nil
+	}
+
+	sp, ok := scope.Get("tracing.span")
+	if !ok {
+		return nil
+	}
+
+	return sp.(*trace.Span)
+}
+
+// deferCPILog holds a line of the CPI log until the request it was logged
+// during is seen, since the CPI log is read before the debug log.
+func (l *Observer) deferCPILog(correlation string, msg log.Line) {
+	if l.pendingCPILogs == nil {
+		l.pendingCPILogs = map[string][]log.Line{}
+	}
+
+	if _, known := l.pendingCPILogs[correlation]; !known {
+		l.pendingCPIRequests = append(l.pendingCPIRequests, correlation)
+	}
+
+	l.pendingCPILogs[correlation] = append(l.pendingCPILogs[correlation], msg)
+}
+
+// handleDeferredCPILogs handles the lines held for a request once it was seen.
+func (l *Observer) handleDeferredCPILogs(correlation string) error {
+	msgs, found := l.pendingCPILogs[correlation]
+	if !found {
+		return nil
+	}
+
+	delete(l.pendingCPILogs, correlation)
+
+	for idx, pending := range l.pendingCPIRequests {
+		if pending == correlation {
+			l.pendingCPIRequests = append(l.pendingCPIRequests[:idx], l.pendingCPIRequests[idx+1:]...)
+
+			break
+		}
+	}
+
+	for _, msg := range msgs {
+		err := l.cpiLogLine(msg)
+		if err != nil {
+			return log.NewLineError(msg, err)
+		}
+	}
+
+	return nil
+}
+
+// flushDeferredCPILogs handles the lines of requests which were never seen,
+// nesting them wherever their parent is guessed to be.
+func (l *Observer) flushDeferredCPILogs() error {
+	for len(l.pendingCPIRequests) > 0 {
+		err := l.handleDeferredCPILogs(l.pendingCPIRequests[0])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (l *Observer) cpiLogLine(msg log.Line) error {
+	switch m := msg.(type) {
+	case taskdebug.CPIAWSRPCMessage:
+		return l.cpiAWSRPC(m)
+	case taskdebug.CPIWaitMessage:
+		return l.cpiWait(m)
+	case taskdebug.CPILogMessage:
+		return l.cpiLog(m)
+	}
+
+	return nil
+}
+
+func (l *Observer) cpiLog(msg taskdebug.CPILogMessage) error {
+	sp := l.findParentSpan(context.Annotations{{Key: "external_cpi.correlation", Value: msg.Correlation}})
+	if sp == nil {
+		return nil
+	}
+
+	fields := []trace.Tag{
+		{Key: "level", Value: msg.LogLevel},
+		{Key: "message", Value: msg.Message},
+	}
+
+	if l.includeLogReferences {
+		fields = append(fields, trace.Tag{Key: "line", Value: msg.LineOffset()})
+
+		if source := msg.LineSource(); source != "" {
+			fields = append(fields, trace.Tag{Key: "source", Value: source})
+		}
+	}
+
+	sp.AddEvent(msg.LogTime, "cpi.log", fields...)
+
+	return nil
+}
+
+func (l *Observer) cpiWait(msg taskdebug.CPIWaitMessage) error {
+	scope := l.ctx.Open(
+		context.Annotation{Key: "external_cpi.correlation", Value: msg.Correlation},
+		context.Annotation{Key: "cpi.wait.resource", Value: msg.Resource},
+	)
+
+	if msg.Event == "start" {
+		sp := l.startSpan(
+			"cpi",
+			"wait: "+msg.Resource,
+			l.findParentSpan(context.Annotations{{Key: "external_cpi.correlation", Value: msg.Correlation}}),
+			msg.LogTime,
+			trace.Tag{Key: "cpi.wait.resource", Value: msg.Resource},
+			trace.Tag{Key: "cpi.wait.state", Value: msg.State},
+		)
+
+		l.addSpanLogReference(sp, "start", msg)
+		scope.Set("tracing.span", sp)
+
+		return nil
+	}
+
+	var sp *trace.Span
+
+	if spU, ok := scope.Get("tracing.span"); ok && !spU.(*trace.Span).Finished {
+		sp = spU.(*trace.Span)
+	} else {
+		// the start was not logged, but the duration is known
+		sp = l.startSpan(
+			"cpi",
+			"wait: "+msg.Resource,
+			l.findParentSpan(context.Annotations{{Key: "external_cpi.correlation", Value: msg.Correlation}}),
+			msg.LogTime.Add(-1*msg.Duration),
+			trace.Tag{Key: "cpi.wait.resource", Value: msg.Resource},
+			trace.Tag{Key: "cpi.wait.state", Value: msg.State},
+		)
+	}
+
+	l.addSpanLogReference(sp, "finish", msg)
+	l.finishSpan(sp, msg.LogTime)
+
+	return nil
+}
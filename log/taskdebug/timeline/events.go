@@ -0,0 +1,209 @@
+package timeline
+
+import (
+	"strings"
+	"time"
+
+	"github.com/dpb587/bosh-log-tracer/log/taskevent"
+	"github.com/dpb587/bosh-log-tracer/observer"
+	"github.com/dpb587/bosh-log-tracer/trace"
+)
+
+// eventStage is a stage of the event log, which replaces the emulated stages
+// when a task's event log is read before its debug log.
+type eventStage struct {
+	key   string
+	name  string
+	tags  []string
+	span  *trace.Span
+	tasks []eventTask
+	done  int
+}
+
+type eventTask struct {
+	name string
+	span *trace.Span
+}
+
+// rootSpanAt returns the root span, starting it if the event log is read
+// before the debug log which otherwise starts it.
+func (l *Observer) rootSpanAt(t time.Time) *trace.Span {
+	if l.rootSpan == nil {
+		l.rootSpan = l.startSpan("worker", l.stages.Operation, nil, t)
+	}
+
+	return l.rootSpan
+}
+
+func (l *Observer) event(msg taskevent.EventMessage) error {
+	root := l.rootSpanAt(msg.Time)
+
+	var stage *eventStage
+
+	for _, s := range l.eventStages {
+		if s.key == msg.StageKey() && !s.span.Finished {
+			stage = s
+		}
+	}
+
+	if stage == nil {
+		sp := l.startSpan(
+			"stage",
+			strings.Join(append([]string{msg.Stage}, msg.Tags...), " "),
+			root,
+			msg.Time,
+			trace.Tag{Key: "stage.total", Value: msg.Total},
+		)
+
+		l.addSpanLogReference(sp, "start", msg)
+
+		stage = &eventStage{
+			key:  msg.StageKey(),
+			name: msg.Stage,
+			tags: msg.Tags,
+			span: sp,
+		}
+
+		l.eventStages = append(l.eventStages, stage)
+	}
+
+	if msg.State == "started" {
+		sp := l.startSpan(
+			"stage",
+			msg.Task,
+			stage.span,
+			msg.Time,
+			trace.Tag{Key: "stage.index", Value: msg.Index},
+		)
+
+		l.addSpanLogReference(sp, "start", msg)
+
+		stage.tasks = append(stage.tasks, eventTask{name: msg.Task, span: sp})
+
+		return nil
+	}
+
+	var sp *trace.Span
+
+	for _, task := range stage.tasks {
+		if task.name == msg.Task && !task.span.Finished {
+			sp = task.span
+		}
+	}
+
+	if sp == nil {
+		return observer.InconsistencyError{Expected: "started event of the task"}
+	}
+
+	switch msg.State {
+	case "in_progress":
+		sp.SetTag("stage.progress", msg.Progress)
+	case "finished", "failed":
+		if msg.State == "failed" {
+			sp.SetError(msg.Time, "failed", msg.Error)
+			stage.span.SetError(msg.Time, "failed", msg.Error)
+		}
+
+		l.addSpanLogReference(sp, "finish", msg)
+		l.finishSpan(sp, msg.Time)
+
+		stage.done++
+
+		if stage.done >= msg.Total {
+			l.addSpanLogReference(stage.span, "finish", msg)
+			l.finishSpan(stage.span, msg.Time)
+		}
+	}
+
+	return nil
+}
+
+func (l *Observer) eventError(msg taskevent.ErrorMessage) error {
+	root := l.rootSpanAt(msg.Time)
+
+	root.SetError(msg.Time, "error", msg.Message, trace.Tag{Key: "error.code", Value: msg.Code})
+	l.addSpanLogReference(root, "error", msg)
+
+	return nil
+}
+
+// findEvent returns the stage of the event log which the current message
+// happened during, along with the span of the stage's task it is about or
+// otherwise of the stage itself. Since events are only logged to the second,
+// spans are considered to last for the second after they finished as well.
+func (l *Observer) findEvent() (*eventStage, *trace.Span) {
+	msg := l.lastMessage
+
+	var prefixes []string
+
+	group := msg.Tags["instance_group"]
+
+	if id := msg.Tags["instance_id"]; group != "" && id != "" {
+		// web/6318b9e7-8c72-4c4e-8769-e59abaa32297 (0) (canary)
+		prefixes = append(prefixes, group+"/"+id+" ")
+	}
+
+	if name := msg.Tags["package_name"]; name != "" {
+		// golang-1.12-linux/8a1f2a8c0a3d4b5e9d3c2e4b1f7a6c5d8e9f0a1b
+		prefixes = append(prefixes, name+"/")
+	}
+
+	var latest, tagged *eventStage
+
+	for idx := len(l.eventStages) - 1; idx >= 0; idx-- {
+		stage := l.eventStages[idx]
+		if !eventCovers(stage.span, msg.LogTime) {
+			continue
+		}
+
+		for _, task := range stage.tasks {
+			if eventCovers(task.span, msg.LogTime) && hasAnyPrefix(task.name, prefixes) {
+				return stage, task.span
+			}
+		}
+
+		if latest == nil {
+			latest = stage
+		}
+
+		if tagged == nil && group != "" && hasTag(stage.tags, group) {
+			tagged = stage
+		}
+	}
+
+	if tagged != nil {
+		return tagged, tagged.span
+	} else if latest != nil {
+		return latest, latest.span
+	}
+
+	return nil, nil
+}
+
+func eventCovers(sp *trace.Span, t time.Time) bool {
+	if t.Before(sp.StartTime) {
+		return false
+	}
+
+	return !sp.Finished || t.Before(sp.FinishTime.Add(time.Second))
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,56 @@
+package timeline
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/input"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug/parser"
+	"github.com/dpb587/bosh-log-tracer/observer"
+	"github.com/dpb587/bosh-log-tracer/observer/context"
+	"github.com/dpb587/bosh-log-tracer/pipeline"
+)
+
+// FuzzPipeline checks parsing and observing copes with arbitrary logs, seeded
+// by the fixtures (go test -fuzz=FuzzPipeline ./log/taskdebug/timeline).
+func FuzzPipeline(f *testing.F) {
+	paths, err := filepath.Glob(filepath.Join("..", "testdata", "*.log"))
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+
+		f.Add(data)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		path := filepath.Join(t.TempDir(), "debug")
+
+		err := ioutil.WriteFile(path, data, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// errors are expected for inconsistent logs; only panics are a problem,
+		// including while leniently continuing after errors
+		pipeline.Run(input.NewFileInput(path), parser.NewParser(parser.Options{}), NewObserver(&context.Context{}, ObserverOptions{
+			IncludeLogReferences: true,
+			DeterministicIDs:     true,
+		}))
+
+		diagnostics := &log.Diagnostics{}
+
+		pipeline.Run(
+			input.NewFileInput(path),
+			parser.NewLenientParser(parser.Options{}, diagnostics),
+			observer.NewLenientObserver(NewObserver(&context.Context{}, ObserverOptions{}), diagnostics),
+		)
+	})
+}
@@ -0,0 +1,1369 @@
+package timeline
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug"
+	"github.com/dpb587/bosh-log-tracer/log/taskevent"
+	"github.com/dpb587/bosh-log-tracer/observer"
+	"github.com/dpb587/bosh-log-tracer/observer/context"
+	"github.com/dpb587/bosh-log-tracer/redact"
+	"github.com/dpb587/bosh-log-tracer/trace"
+)
+
+// Observer correlates task debug messages into the spans of a trace, leaving
+// the format and destination of the trace to exporters.
+type Observer struct {
+	ctx   *context.Context
+	trace *trace.Trace
+
+	rootSpan               *trace.Span
+	lastMessage            taskdebug.RawMessage
+	stages                 stageModel
+	emulatedStage          string
+	eventStages            []*eventStage
+	updatingInstanceGroups []string
+	releasePackageSpan     *trace.Span
+
+	// lines of the CPI log waiting for their request, in the order in which
+	// requests were first mentioned
+	pendingCPILogs     map[string][]log.Line
+	pendingCPIRequests []string
+
+	// for deriving IDs once committed
+	directorID string
+	taskID     string
+
+	includeLogReferences bool
+	deterministicIDs     bool
+	redactor             *redact.Redactor
+}
+
+type ObserverOptions struct {
+	IncludeLogReferences bool
+
+	// IDGenerator creates trace and span IDs; they are random by default.
+	IDGenerator trace.IDGenerator
+
+	// DeterministicIDs derives the trace ID from the director and task ID, and
+	// span IDs from their keys, so tracing the same log results in the same
+	// IDs.
+	DeterministicIDs bool
+
+	// Redactor removes sensitive data from the trace once committed.
+	Redactor *redact.Redactor
+}
+
+var _ observer.Observer = &Observer{}
+
+func NewObserver(ctx *context.Context, o ObserverOptions) *Observer {
+	return &Observer{
+		ctx:                  ctx,
+		trace:                trace.NewTrace(o.IDGenerator),
+		stages:               deployStageModel,
+		includeLogReferences: o.IncludeLogReferences,
+		deterministicIDs:     o.DeterministicIDs,
+		redactor:             o.Redactor,
+	}
+}
+
+// Trace returns the spans built so far; it is complete once committed.
+func (l *Observer) Trace() *trace.Trace {
+	return l.trace
+}
+
+func (l *Observer) startSpan(service, operationName string, parent *trace.Span, startTime time.Time, tags ...trace.Tag) *trace.Span {
+	return l.trace.StartSpan(service, operationName, parent, startTime, tags...)
+}
+
+func (l *Observer) finishSpan(sp *trace.Span, finishTime time.Time) {
+	sp.Finish(finishTime)
+}
+
+// finishIncompleteSpans closes any spans which never saw their finishing
+// message, most recently started first, at the time of the last message.
+func (l *Observer) finishIncompleteSpans() {
+	spans := l.trace.Spans
+
+	for idx := len(spans) - 1; idx >= 0; idx-- {
+		sp := spans[idx]
+		if sp.Finished || sp == l.rootSpan {
+			continue
+		}
+
+		sp.SetTag("incomplete", true)
+		l.finishSpan(sp, l.lastMessage.LogTime)
+	}
+}
+
+func (l *Observer) Begin() error {
+	return nil
+}
+
+func (l *Observer) Commit() error {
+	for _, group := range l.updatingInstanceGroups {
+		ctx := l.ctx.Open(
+			context.Annotation{Key: "updater", Value: "instance_group"},
+			context.Annotation{Key: "updater.instance_group", Value: group},
+		)
+
+		lastMessage, ok := ctx.Get("last_message")
+		if !ok {
+			continue // because of no-op instance groups?
+		}
+
+		igspU, ok := ctx.Get("tracing.span")
+		if !ok {
+			return observer.InconsistencyError{Expected: "instance group start span"}
+		}
+
+		l.finishSpan(igspU.(*trace.Span), lastMessage.(taskdebug.NATSMessageMessage).LogTime)
+	}
+
+	err := l.flushDeferredCPILogs()
+	if err != nil {
+		return err
+	}
+
+	if l.rootSpan != nil {
+		err := l.endEmulatedStage(l.lastMessage)
+		if err != nil {
+			return err
+		}
+	}
+
+	l.finishIncompleteSpans()
+
+	if l.rootSpan != nil {
+		l.finishSpan(l.rootSpan, l.lastMessage.LogTime)
+	}
+
+	l.prepareTrace()
+
+	return nil
+}
+
+// Flush prepares the spans built so far to be exported while the task is still
+// in progress.
+func (l *Observer) Flush() error {
+	l.prepareTrace()
+
+	return nil
+}
+
+// prepareTrace derives IDs, once the task is known, and redacts the trace.
+// Both may be repeated as more spans are added.
+func (l *Observer) prepareTrace() {
+	if l.deterministicIDs && l.taskID != "" {
+		l.trace.DeriveIDs(fmt.Sprintf("bosh-director/%s/task/%s", l.directorID, l.taskID))
+	}
+
+	l.redactor.Trace(l.trace)
+}
+
+// IDsKnown reports whether the IDs of the trace are final. When they are
+// derived from the task, spans exported before it is known would end up in
+// another trace.
+func (l *Observer) IDsKnown() bool {
+	return !l.deterministicIDs || l.taskID != ""
+}
+
+// LastMessageTime is the time of the most recent message with a timestamp,
+// which is as far as the task is known to have progressed.
+func (l *Observer) LastMessageTime() time.Time {
+	return l.lastMessage.LogTime
+}
+
+func (l *Observer) Handle(msg log.Line) error {
+	return log.NewLineError(msg, l.handle(msg))
+}
+
+func (l *Observer) handle(msg log.Line) error {
+	if correlation := cpiLogCorrelation(msg); correlation != "" && l.findCPIRequestSpan(correlation) == nil {
+		// the CPI log is read before the debug log which has the request
+		l.deferCPILog(correlation, msg)
+
+		return nil
+	}
+
+	if v, ok := msg.(taskdebug.RawMessageGetter); ok {
+		// for closing our final span at the end
+		if raw := v.GetRawMessage(); !raw.LogTime.IsZero() {
+			l.lastMessage = raw
+		}
+	}
+
+	if len(l.eventStages) == 0 {
+		// the event log, if any, has the actual stages
+		err := l.triggerEmulatedStage(msg)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch m := msg.(type) {
+	case taskevent.EventMessage:
+		if m.Time.After(l.lastMessage.LogTime) {
+			l.lastMessage = taskdebug.RawMessage{LogTime: m.Time}
+		}
+
+		return l.event(m)
+	case taskevent.ErrorMessage:
+		return l.eventError(m)
+
+	case taskdebug.ProcessMessage:
+		return l.process(m)
+	case taskdebug.TaskMessage:
+		return l.task(m)
+
+	case taskdebug.ErrorMessage:
+		return l.error(m)
+	case taskdebug.TaskStateMessage:
+		return l.taskState(m)
+
+	case taskdebug.SequelMessage:
+		// shouldn't these be redacted?
+
+		if m.Tags["action"] != "compile_package" || !strings.Contains(m.Query, `INSERT INTO "events" `) {
+			break
+		}
+
+		// hacky hacky correlate the future vm name to a package
+		// the create_vm calls do not have any package-specific details
+		// TODO mysql; or just a better way
+		if split := strings.SplitN(m.Query, `'create', 'instance', '`, 2); len(split) == 2 {
+			instance := strings.SplitN(split[1], `', `, 2)[0]
+
+			// should already exist
+			ctx := l.ctx.Open(
+				context.Annotation{Key: "compilation.package", Value: m.Tags["package"]},
+				context.Annotation{Key: "compilation.stemcell", Value: m.Tags["stemcell"]},
+			)
+			ctx.AddAnnotation(context.Annotation{Key: "expected_compilation_instance", Value: instance})
+		}
+
+		// noisy; ignore for now
+		// return l.sequel(m)
+
+	case taskdebug.NATSMessageSentAgentMessage:
+		return l.natsSentAgent(m)
+	case taskdebug.NATSMessageMessage:
+		if m.Event == "RECEIVED" {
+			return l.natsReceived(m)
+		} else if m.Event == "SENT" && strings.HasPrefix(m.Channel, "hm.") {
+			return l.natsSentHM(m)
+		}
+
+	case taskdebug.ExternalCPIRequestMessage:
+		return l.externalCPIRequest(m)
+	case taskdebug.ExternalCPIResponseMessage:
+		return l.externalCPIResponse(m)
+	case taskdebug.ExternalCPIMessage:
+		if m.Event == "response" {
+			// the payload could not be parsed, but the request is still done
+			return l.externalCPIResponse(taskdebug.ExternalCPIResponseMessage{ExternalCPIMessage: m})
+		}
+
+	case taskdebug.CPIAWSRPCMessage, taskdebug.CPIWaitMessage, taskdebug.CPILogMessage:
+		return l.cpiLogLine(m)
+
+	case taskdebug.LockMessage:
+		return l.lock(m)
+
+	case taskdebug.InstanceAspectChangedMessage:
+		return l.instanceAspectChanged(m)
+
+	case taskdebug.ArtifactStepMessage:
+		return l.artifactStep(m)
+	case taskdebug.ReleasePackageMessage:
+		return l.releasePackage(m)
+	case taskdebug.BlobstoreMessage:
+		return l.blobstore(m)
+
+	case taskdebug.RawMessage:
+		if m.Message == "Creating job" {
+			return l.creatingJob(m)
+		} else if strings.HasPrefix(m.Message, "Compiling package '") {
+			// msg.Tags["action"] == "compile_package"
+			return l.startPackageCompilation(m)
+		} else if strings.HasPrefix(m.Message, "Finished compiling package '") {
+			// msg.Tags["action"] == "compile_package"
+			return l.finishPackageCompilation(m)
+		} else if strings.HasPrefix(m.Message, "Updating instance ") {
+			return l.startUpdateInstance(m)
+		} else if strings.HasPrefix(m.Message, "Creating missing VM") {
+			return l.startCreateInstance(m)
+		} else if strings.HasPrefix(m.Message, "Agenda step Bosh::Director::DeploymentPlan::Steps::RenderInstanceJobTemplatesStep finished") {
+			// TODO formal agenda step
+			return l.finishCreateInstance(m)
+		}
+	}
+
+	return nil
+}
+
+func (l *Observer) instanceAspectChanged(msg taskdebug.InstanceAspectChangedMessage) error {
+	ctx := l.ctx.Open(
+		context.Annotation{Key: "aggregation", Value: "instance_aspect_changed"},
+		context.Annotation{Key: "instance_group", Value: msg.InstanceGroup},
+		context.Annotation{Key: "instance_id", Value: msg.InstanceID},
+	)
+
+	// should only ever be one per aspect
+	// weirdly, seems like stemcell_changed? can appear multiple times
+	ctx.Set(msg.Aspect, msg)
+
+	return nil
+}
+
+func (l *Observer) startUpdateInstance(msg taskdebug.RawMessage) error {
+	var igsp *trace.Span
+
+	if len(l.eventStages) > 0 {
+		// the event log already has a stage for the instance group and a task
+		// for the instance
+		igsp = l.findParentSpan()
+	} else {
+		igsp = l.startUpdateInstanceGroup(msg)
+	}
+
+	ctx := l.ctx.Open(
+		context.Annotation{Key: "updater", Value: "instance_id"},
+		context.Annotation{Key: "updater.instance_group", Value: msg.Tags["instance_group"]},
+		context.Annotation{Key: "updater.instance_id", Value: msg.Tags["instance_id"]},
+	)
+	_, ok := ctx.Get("tracing.span")
+	if !ok {
+		sp := l.startSpan(
+			"updater",
+			fmt.Sprintf("id: %s", msg.Tags["instance_id"]),
+			igsp,
+			msg.LogTime,
+			trace.Tag{Key: "instance_group", Value: msg.Tags["instance_group"]},
+			trace.Tag{Key: "instance_id", Value: msg.Tags["instance_id"]},
+		)
+
+		l.addSpanLogReference(sp, "start", msg)
+
+		aspectsCtx := l.ctx.Open(
+			context.Annotation{Key: "aggregation", Value: "instance_aspect_changed"},
+			context.Annotation{Key: "instance_group", Value: msg.Tags["instance_group"]},
+			context.Annotation{Key: "instance_id", Value: msg.Tags["instance_id"]},
+		)
+
+		aspectChanges := aspectsCtx.Keys()
+		sort.Strings(aspectChanges)
+
+		for _, k := range aspectChanges {
+			msgAspectU, _ := aspectsCtx.Get(k)
+			msgAspect := msgAspectU.(taskdebug.InstanceAspectChangedMessage)
+
+			changedFromTags, err := msgAspect.GetChangedFromTags()
+			if err != nil {
+				return log.NewLineError(msgAspect, err)
+			}
+
+			for k, v := range changedFromTags {
+				sp.SetTag(strings.TrimSuffix(fmt.Sprintf("updater.change.%s.old.%s", msgAspect.Aspect, k), "."), v)
+			}
+
+			changedToTags, err := msgAspect.GetChangedToTags()
+			if err != nil {
+				return log.NewLineError(msgAspect, err)
+			}
+
+			for k, v := range changedToTags {
+				sp.SetTag(strings.TrimSuffix(fmt.Sprintf("updater.change.%s.new.%s", msgAspect.Aspect, k), "."), v)
+			}
+
+			if msgAspect.Aspect == "packages" {
+				sp.SetTag("updater.change.packages", msgAspect.GetChangedPackages())
+			}
+
+			l.addSpanLogReference(sp, "changed", msgAspect)
+		}
+
+		if len(aspectChanges) > 0 {
+			sp.SetTag("updater.changes", aspectChanges)
+		}
+
+		ctx.Set("tracing.span", sp)
+	}
+
+	return nil
+}
+
+// startUpdateInstanceGroup returns the span of the instance group, starting it
+// for the first of its instances.
+func (l *Observer) startUpdateInstanceGroup(msg taskdebug.RawMessage) *trace.Span {
+	ctx := l.ctx.Open(
+		context.Annotation{Key: "updater", Value: "instance_group"},
+		context.Annotation{Key: "updater.instance_group", Value: msg.Tags["instance_group"]},
+	)
+
+	if igspU, ok := ctx.Get("tracing.span"); ok {
+		return igspU.(*trace.Span)
+	}
+
+	igsp := l.startSpan(
+		"updater",
+		fmt.Sprintf("group: %s", msg.Tags["instance_group"]),
+		l.findParentSpan(),
+		msg.LogTime,
+		trace.Tag{Key: "instance_group", Value: msg.Tags["instance_group"]},
+	)
+
+	ctx.Set("tracing.span", igsp)
+	l.updatingInstanceGroups = append(l.updatingInstanceGroups, msg.Tags["instance_group"])
+
+	return igsp
+}
+
+// updatingInstances is whether VMs are created for instances rather than for
+// compiling packages.
+func (l *Observer) updatingInstances() bool {
+	if stage, _ := l.findEvent(); stage != nil {
+		return stage.name != "Compiling packages"
+	}
+
+	return l.emulatedStage == "updating"
+}
+
+func (l *Observer) startCreateInstance(msg taskdebug.RawMessage) error {
+	if !l.updatingInstances() {
+		// don't do this for compilations/preparations
+		return nil
+	}
+
+	// if there's an updater scope, just use that
+	updaterCtx := l.ctx.Open(
+		context.Annotation{Key: "updater", Value: "instance_id"},
+		context.Annotation{Key: "updater.instance_group", Value: msg.Tags["instance_group"]},
+		context.Annotation{Key: "updater.instance_id", Value: msg.Tags["instance_id"]},
+	)
+	_, ok := updaterCtx.Get("tracing.span")
+	if ok {
+		return nil
+	}
+
+	ctx := l.ctx.Open(
+		context.Annotation{Key: "creator", Value: "instance_id"},
+		context.Annotation{Key: "creator.instance_group", Value: msg.Tags["instance_group"]},
+		context.Annotation{Key: "creator.instance_id", Value: msg.Tags["instance_id"]},
+	)
+	_, ok = ctx.Get("tracing.span")
+	if !ok {
+		sp := l.startSpan(
+			"creator",
+			fmt.Sprintf("%s/%s", msg.Tags["instance_group"], msg.Tags["instance_id"]),
+			l.findParentSpan(),
+			msg.LogTime,
+			trace.Tag{Key: "instance_group", Value: msg.Tags["instance_group"]},
+			trace.Tag{Key: "instance_id", Value: msg.Tags["instance_id"]},
+		)
+
+		l.addSpanLogReference(sp, "start", msg)
+
+		ctx.Set("tracing.span", sp)
+	}
+
+	return nil
+}
+
+func (l *Observer) finishCreateInstance(msg taskdebug.RawMessage) error {
+	var sp *trace.Span
+
+	ctx := l.ctx.Open(
+		context.Annotation{Key: "creator", Value: "instance_id"},
+		context.Annotation{Key: "creator.instance_group", Value: msg.Tags["instance_group"]},
+		context.Annotation{Key: "creator.instance_id", Value: msg.Tags["instance_id"]},
+	)
+	spU, ok := ctx.Get("tracing.span")
+	if !ok {
+		return nil
+	}
+
+	sp = spU.(*trace.Span)
+	l.finishSpan(sp, msg.LogTime)
+	l.addSpanLogReference(sp, "finish", msg)
+
+	return nil
+}
+
+func (l *Observer) finishUpdateInstance(start taskdebug.NATSMessageSentAgentMessage, end taskdebug.NATSMessageMessage) error {
+	// original sending message has the metadata we need to correlate
+
+	ctx := l.ctx.Open(
+		context.Annotation{Key: "updater", Value: "instance_id"},
+		context.Annotation{Key: "updater.instance_group", Value: start.Tags["instance_group"]},
+		context.Annotation{Key: "updater.instance_id", Value: start.Tags["instance_id"]},
+	)
+	spU, ok := ctx.Get("tracing.span")
+	if !ok {
+		return observer.InconsistencyError{Expected: "instance start span"}
+	}
+
+	sp := spU.(*trace.Span)
+	l.finishSpan(sp, end.LogTime)
+	l.addSpanLogReference(sp, "finish", end)
+
+	ctx = l.ctx.Open(
+		context.Annotation{Key: "updater", Value: "instance_group"},
+		context.Annotation{Key: "updater.instance_group", Value: start.Tags["instance_group"]},
+	)
+
+	ctx.Set("last_message", end)
+
+	return nil
+}
+
+func (l *Observer) startPackageCompilation(msg taskdebug.RawMessage) error {
+	sp := l.startSpan(
+		"compiler",
+		fmt.Sprintf("compile: %s", msg.Tags["package_name"]),
+		l.findParentSpan(),
+		msg.LogTime,
+		trace.Tag{Key: "package_name", Value: msg.Tags["package_name"]},
+		trace.Tag{Key: "package_fingerprint", Value: msg.Tags["package_fingerprint"]},
+		trace.Tag{Key: "stemcell_os", Value: msg.Tags["stemcell_os"]},
+		trace.Tag{Key: "stemcell_version", Value: msg.Tags["stemcell_version"]},
+	)
+
+	l.addSpanLogReference(sp, "start", msg)
+
+	ctx := l.ctx.Open(
+		context.Annotation{Key: "compilation.package", Value: msg.Tags["package"]},
+		context.Annotation{Key: "compilation.stemcell", Value: msg.Tags["stemcell"]},
+	)
+	ctx.Set("tracing.span", sp)
+
+	return nil
+}
+
+func (l *Observer) finishPackageCompilation(msg taskdebug.RawMessage) error {
+	ctx := l.ctx.Open(
+		context.Annotation{Key: "compilation.package", Value: msg.Tags["package"]},
+		context.Annotation{Key: "compilation.stemcell", Value: msg.Tags["stemcell"]},
+	)
+	spU, ok := ctx.Get("tracing.span")
+	if !ok {
+		return observer.InconsistencyError{Expected: "package compilation span"}
+	}
+
+	sp := spU.(*trace.Span)
+	l.addSpanLogReference(sp, "finish", msg)
+	l.finishSpan(sp, msg.LogTime)
+
+	return nil
+}
+
+func (l *Observer) getDefaultAnnotations(msg taskdebug.RawMessage) []context.Annotations {
+	var res []context.Annotations
+
+	action, ok := msg.Tags["action"]
+	if ok {
+		if action == "compile_package" {
+			res = append(
+				res,
+				context.Annotations{
+					{Key: "compilation.package", Value: msg.Tags["package"]},
+					{Key: "compilation.stemcell", Value: msg.Tags["stemcell"]},
+				},
+			)
+		} else if action == "canary_update" || action == "instance_update" || action == "create_missing_vm" {
+			ig, ok1 := msg.Tags["instance_group"]
+			igid, ok2 := msg.Tags["instance_id"]
+			if ok1 && ok2 {
+				res = append(
+					res,
+					context.Annotations{
+						{Key: "updater", Value: "instance_id"},
+						{Key: "updater.instance_group", Value: ig},
+						{Key: "updater.instance_id", Value: igid},
+					},
+				)
+
+				if strings.HasPrefix(ig, "compilation-") {
+					res = append(
+						res,
+						context.Annotations{
+							{Key: "expected_compilation_instance", Value: fmt.Sprintf("%s/%s", ig, igid)},
+						},
+					)
+				}
+
+				res = append(
+					res,
+					context.Annotations{
+						{Key: "creator", Value: "instance_id"},
+						{Key: "creator.instance_group", Value: ig},
+						{Key: "creator.instance_id", Value: igid},
+					},
+				)
+			}
+		}
+	}
+
+	return res
+}
+
+func (l *Observer) findParentSpan(priorities ...context.Annotations) *trace.Span {
+	merged := priorities
+
+	if l.emulatedStage != "" {
+		merged = append(
+			merged,
+			context.Annotations{
+				{
+					Key:   "emulated_stage",
+					Value: l.emulatedStage,
+				},
+			},
+		)
+	}
+
+	for _, annotations := range merged {
+		scope := l.ctx.Find(annotations...)
+		if scope != nil {
+			span, ok := scope.Get("tracing.span")
+			if !ok {
+				// error?
+				continue
+			}
+
+			return span.(*trace.Span)
+		}
+	}
+
+	if _, sp := l.findEvent(); sp != nil {
+		return sp
+	}
+
+	return l.rootSpan
+}
+
+func (l *Observer) triggerEmulatedStage(msg log.Line) error {
+	for _, trigger := range l.stages.Triggers {
+		raw, ok := trigger.matches(l.emulatedStage, msg)
+		if !ok {
+			continue
+		} else if trigger.Stage == l.emulatedStage {
+			return nil
+		}
+
+		return l.startEmulatedStage(raw, trigger.Stage)
+	}
+
+	return nil
+}
+
+func (l *Observer) startEmulatedStage(msg taskdebug.RawMessage, stage string) error {
+	err := l.endEmulatedStage(msg)
+	if err != nil {
+		return err
+	}
+
+	sp := l.startSpan(
+		"stage",
+		stage,
+		l.rootSpan,
+		msg.LogTime,
+	)
+
+	l.emulatedStage = stage
+	l.addSpanLogReference(sp, "start", msg)
+
+	ctx := l.ctx.Open(context.Annotation{Key: "emulated_stage", Value: stage})
+	ctx.Set("tracing.span", sp)
+
+	return nil
+}
+
+func (l *Observer) endEmulatedStage(msg taskdebug.RawMessage) error {
+	l.finishReleasePackage(msg)
+
+	if l.emulatedStage == "" {
+		return nil
+	}
+
+	ctx := l.ctx.Open(context.Annotation{Key: "emulated_stage", Value: l.emulatedStage})
+	spU, ok := ctx.Get("tracing.span")
+	if !ok {
+		return observer.InconsistencyError{Expected: "emulated stage span"}
+	}
+
+	sp := spU.(*trace.Span)
+	l.addSpanLogReference(sp, "start", msg)
+	l.finishSpan(sp, msg.LogTime)
+
+	l.emulatedStage = ""
+
+	return nil
+}
+
+func (l *Observer) process(msg taskdebug.ProcessMessage) error {
+	tags := []trace.Tag{
+		{Key: "ref", Value: "thirteen"}, // dev search correlation
+		{Key: "director.worker", Value: msg.WorkerName},
+		{Key: "director.instance.name", Value: msg.InstanceName},
+		{Key: "director.instance.id", Value: msg.InstanceID},
+		{Key: "host.ip", Value: msg.IP},
+	}
+
+	sp := l.rootSpan
+
+	if sp == nil {
+		sp = l.startSpan("worker", l.stages.Operation, nil, msg.LogTime, tags...)
+	} else {
+		// already started by the event log, which is only logged to the second
+		for _, tag := range tags {
+			sp.SetTag(tag.Key, tag.Value)
+		}
+
+		if msg.LogTime.Before(sp.StartTime) {
+			sp.StartTime = msg.LogTime
+		}
+	}
+
+	l.addSpanLogReference(sp, "start", msg)
+
+	l.rootSpan = sp
+	l.directorID = msg.InstanceID
+
+	return nil
+}
+
+func (l *Observer) creatingJob(msg taskdebug.RawMessage) error {
+	if l.rootSpan == nil {
+		return observer.InconsistencyError{Expected: "root span by this time"}
+	}
+
+	l.rootSpan.SetTag("task", msg.Tags["task"])
+	l.taskID = msg.Tags["task"]
+
+	return nil
+}
+
+func (l *Observer) error(msg taskdebug.ErrorMessage) error {
+	if l.rootSpan == nil {
+		return nil
+	}
+
+	errorKind := msg.ErrorType
+	if errorKind == "" {
+		errorKind = "error"
+	}
+
+	affectedSpans := []*trace.Span{
+		l.findParentSpan(l.getDefaultAnnotations(msg.RawMessage)...),
+	}
+
+	if l.emulatedStage != "" {
+		if scope := l.ctx.Find(context.Annotation{Key: "emulated_stage", Value: l.emulatedStage}); scope != nil {
+			if spU, ok := scope.Get("tracing.span"); ok {
+				affectedSpans = append(affectedSpans, spU.(*trace.Span))
+			}
+		}
+	} else if stage, _ := l.findEvent(); stage != nil {
+		affectedSpans = append(affectedSpans, stage.span)
+	}
+
+	affectedSpans = append(affectedSpans, l.rootSpan)
+
+	var fields []trace.Tag
+
+	if len(msg.Backtrace) > 0 {
+		fields = append(fields, trace.Tag{Key: "stack", Value: strings.Join(msg.Backtrace, "\n")})
+	}
+
+	marked := map[*trace.Span]struct{}{}
+
+	for _, sp := range affectedSpans {
+		if _, found := marked[sp]; found {
+			continue
+		}
+
+		marked[sp] = struct{}{}
+
+		l.setSpanError(sp, errorKind, msg.ErrorMessage, fields...)
+		l.addSpanLogReference(sp, "error", msg)
+	}
+
+	return nil
+}
+
+func (l *Observer) taskState(msg taskdebug.TaskStateMessage) error {
+	if l.rootSpan == nil {
+		return nil
+	}
+
+	l.rootSpan.SetTag("task.state", msg.State)
+
+	if msg.State == "error" && !l.rootSpan.IsError() {
+		l.rootSpan.Status = trace.Status{
+			Code:    trace.StatusError,
+			Message: fmt.Sprintf("task %s %s", msg.TaskID, msg.State),
+		}
+	}
+
+	return nil
+}
+
+func (l *Observer) setSpanError(sp *trace.Span, kind, message string, fields ...trace.Tag) {
+	sp.SetError(l.lastMessage.LogTime, kind, message, fields...)
+}
+
+func (l *Observer) task(msg taskdebug.TaskMessage) error {
+	if msg.Type == "" {
+		return nil
+	}
+
+	l.stages = getStageModel(msg.Type, msg.Description)
+
+	if l.rootSpan == nil {
+		return nil
+	}
+
+	l.rootSpan.SetOperationName(l.stages.Operation)
+	l.rootSpan.SetTag("task", msg.TaskID)
+
+	if l.taskID == "" {
+		l.taskID = msg.TaskID
+	}
+
+	l.rootSpan.SetTag("task.type", msg.Type)
+	l.rootSpan.SetTag("task.description", msg.Description)
+
+	if msg.DeploymentName != "" {
+		l.rootSpan.SetTag("deployment", msg.DeploymentName)
+	}
+
+	return nil
+}
+
+func (l *Observer) sequel(msg taskdebug.SequelMessage) error {
+	if l.rootSpan == nil {
+		// debug queries show up before the startup "process" message
+		return nil
+	}
+
+	operation := strings.SplitN(msg.Query, " ", 2)[0]
+	if operation == "BEGIN" || operation == "COMMIT" {
+		// ignore these for simplicity for now
+		// TODO consider a transaction span
+		return nil
+	}
+
+	sp := l.startSpan(
+		"db",
+		operation,
+		l.findParentSpan(l.getDefaultAnnotations(msg.RawMessage)...),
+		msg.LogTime.Add(-1*msg.Duration),
+	)
+
+	l.addSpanLogReference(sp, "start", msg)
+
+	l.addSpanLogReference(sp, "finish", msg)
+	l.finishSpan(sp, msg.LogTime)
+
+	return nil
+}
+
+func (l *Observer) natsSentHM(msg taskdebug.NATSMessageMessage) error {
+	sp := l.startSpan(
+		"nats",
+		fmt.Sprintf("hm: %s", strings.TrimPrefix(msg.Channel, "hm.director.")),
+		l.findParentSpan(l.getDefaultAnnotations(msg.RawMessage)...),
+		msg.LogTime,
+	)
+
+	l.addSpanLogReference(sp, "start", msg)
+
+	// no response expected, so finish immediately
+	l.addSpanLogReference(sp, "finish", msg)
+	l.finishSpan(sp, msg.LogTime)
+
+	return nil
+}
+
+func (l *Observer) natsSentAgent(msg taskdebug.NATSMessageSentAgentMessage) error {
+	var parentSpan *trace.Span = l.findParentSpan(l.getDefaultAnnotations(msg.RawMessage)...)
+
+	if msg.PayloadMethod == "get_task" {
+		taskID, err := msg.GetArgument0String()
+		if err != nil {
+			return err
+		}
+
+		ctx := l.ctx.Open(context.Annotation{Key: "agent.task_id", Value: taskID})
+		res, ok := ctx.Get("tracing.span")
+		if !ok {
+			return observer.InconsistencyError{Expected: "original task span"}
+		}
+
+		parentSpan = res.(*trace.Span)
+	} else if msg.PayloadMethod == "ping" {
+		// ping is like other methods, except it only ever happens once and involves
+		// spamming multiple SENTs; only span one outer set
+		// this is weird tracing because those other ping attempts are not really
+		// surfaced so they're just dangling spans; might be better to show the
+		// attempt as a 0ms [failed] span?
+
+		ctx := l.ctx.Open(
+			context.Annotation{Key: "agent.id", Value: msg.AgentID},
+			context.Annotation{Key: "agent.method", Value: msg.PayloadMethod},
+		)
+		if sp, started := ctx.Get("tracing.span"); started {
+			parentSpan = sp.(*trace.Span)
+		} else {
+			sp := l.startSpan(
+				"nats",
+				fmt.Sprintf("agent: %s", msg.PayloadMethod),
+				parentSpan,
+				msg.LogTime,
+				trace.Tag{Key: "nats.agent.agent_id", Value: msg.AgentID},
+				trace.Tag{Key: "nats.agent.method", Value: msg.PayloadMethod},
+			)
+
+			l.addSpanLogReference(sp, "start", msg)
+
+			ctx.Set("tracing.span", sp)
+			ctx.Set("nats.sent", msg)
+
+			parentSpan = sp
+		}
+
+	} else if msg.PayloadMethod != "get_state" && msg.PayloadMethod != "start" { // for all other methods which will result in subsequent get_task calls
+		// outer span = method
+		// inner spans = [initial message, get_state...]
+
+		operation := msg.PayloadMethod
+
+		if operation == "run_script" {
+			var err error
+
+			operation, err = msg.GetArgument0String()
+			if err != nil {
+				return err
+			}
+		}
+
+		sp := l.startSpan(
+			"nats",
+			fmt.Sprintf("agent: %s", operation),
+			parentSpan,
+			msg.LogTime,
+			trace.Tag{Key: "nats.agent.agent_id", Value: msg.AgentID},
+			trace.Tag{Key: "nats.agent.method", Value: msg.PayloadMethod},
+		)
+
+		l.addSpanLogReference(sp, "start", msg)
+
+		ctx := l.ctx.Open(
+			context.Annotation{Key: "agent.id", Value: msg.AgentID},
+			context.Annotation{Key: "agent.method", Value: msg.PayloadMethod},
+			context.Annotation{Key: "agent.pending_task_id", Value: msg.PayloadReplyTo},
+		)
+		ctx.Set("tracing.span", sp)
+		ctx.Set("nats.sent", msg)
+
+		parentSpan = sp
+	}
+
+	sp := l.startSpan(
+		"nats",
+		fmt.Sprintf("agent: %s", msg.PayloadMethod),
+		parentSpan,
+		msg.LogTime,
+		trace.Tag{Key: "nats.agent.agent_id", Value: msg.AgentID},
+		trace.Tag{Key: "nats.agent.method", Value: msg.PayloadMethod},
+	)
+
+	l.addSpanLogReference(sp, "start", msg)
+
+	ctx := l.ctx.Open(context.Annotation{Key: "nats.reply_to", Value: msg.PayloadReplyTo})
+	ctx.Set("tracing.span", sp)
+	ctx.Set("nats.sent", msg)
+
+	return nil
+}
+
+func (l *Observer) natsReceived(msg taskdebug.NATSMessageMessage) error {
+	scope := l.ctx.Open(context.Annotation{Key: "nats.reply_to", Value: msg.Channel})
+	spU, ok := scope.Get("tracing.span")
+	if !ok {
+		return observer.InconsistencyError{Expected: "sent message span"}
+	}
+
+	sp := spU.(*trace.Span)
+	l.addSpanLogReference(sp, "finish", msg)
+	l.finishSpan(sp, msg.LogTime)
+
+	sentMsgU, ok := scope.Get("nats.sent")
+	if !ok {
+		return observer.InconsistencyError{Expected: "sent message"}
+	}
+
+	sentMsg := sentMsgU.(taskdebug.NATSMessageSentAgentMessage)
+
+	exception, err := msg.GetReceivedException()
+	if err != nil {
+		return err
+	} else if exception != "" {
+		l.setSpanError(sp, "agent exception", exception)
+	}
+
+	switch sentMsg.PayloadMethod {
+	case "get_task", "ping":
+		state, err := msg.GetReceivedState()
+		if err != nil {
+			return err
+		}
+
+		if state != "running" {
+			// close the outer task span
+			var findAnnotations context.Annotations
+
+			if sentMsg.PayloadMethod == "ping" {
+				findAnnotations = context.Annotations{
+					{Key: "agent.id", Value: sentMsg.AgentID},
+					{Key: "agent.method", Value: sentMsg.PayloadMethod},
+				}
+			} else {
+				taskID, err := sentMsg.GetArgument0String()
+				if err != nil {
+					return log.NewLineError(sentMsg, err)
+				}
+
+				findAnnotations = context.Annotations{
+					{Key: "agent.task_id", Value: taskID},
+				}
+			}
+
+			ctx := l.ctx.Open(findAnnotations...)
+			spU, ok := ctx.Get("tracing.span")
+			if !ok {
+				return observer.InconsistencyError{Expected: "original task span"}
+			}
+
+			sp := spU.(*trace.Span)
+			l.addSpanLogReference(sp, "finish", msg)
+			l.finishSpan(sp, msg.LogTime)
+
+			if exception != "" {
+				l.setSpanError(sp, "agent exception", exception)
+			}
+
+			{ // cheat and assume this is the last step of updating an instance
+				taskMsgU, ok := ctx.Get("nats.sent")
+				if !ok {
+					return observer.InconsistencyError{Expected: "original message"}
+				}
+
+				taskMsg := taskMsgU.(taskdebug.NATSMessageSentAgentMessage)
+
+				if taskMsg.PayloadMethod == "run_script" {
+					script, err := taskMsg.GetArgument0String()
+					if err != nil {
+						return log.NewLineError(taskMsg, err)
+					}
+
+					if script == "post-start" {
+						err = l.finishUpdateInstance(taskMsg, msg)
+						if err != nil {
+							return err
+						}
+					}
+				}
+			}
+		}
+	case "get_state", "start":
+		// nop
+	default:
+		// it should have come back with a task id that we want to annotate for subsequent calls
+		taskID, err := msg.GetReceivedTaskID()
+		if err != nil {
+			return err
+		}
+
+		scope := l.ctx.Open(context.Annotation{Key: "agent.pending_task_id", Value: msg.Channel})
+		scope.AddAnnotation(context.Annotation{Key: "agent.task_id", Value: taskID})
+
+		spU, ok := scope.Get("tracing.span")
+		if !ok {
+			return observer.InconsistencyError{Expected: "wrapping task span"}
+		}
+
+		spU.(*trace.Span).SetTag("nats.agent.task_id", taskID)
+	}
+
+	return nil
+}
+
+func (l *Observer) externalCPIRequest(msg taskdebug.ExternalCPIRequestMessage) error {
+	sp := l.startSpan(
+		"cpi",
+		msg.PayloadMethod,
+		l.findParentSpan(l.getDefaultAnnotations(msg.RawMessage)...),
+		msg.LogTime,
+		trace.Tag{Key: "cpi.method", Value: msg.PayloadMethod},
+		trace.Tag{Key: "cpi.exec", Value: msg.Command},
+	)
+
+	l.addSpanLogReference(sp, "start", msg)
+
+	if msg.PayloadMethod == "create_stemcell" {
+		arguments, err := msg.GetArguments()
+		if err != nil {
+			return err
+		}
+
+		if len(arguments) > 1 {
+			if cloudProperties, ok := arguments[1].(map[string]interface{}); ok {
+				for _, k := range []string{"name", "version", "infrastructure", "hypervisor"} {
+					if v, ok := cloudProperties[k]; ok {
+						sp.SetTag(fmt.Sprintf("cpi.stemcell.%s", k), v)
+					}
+				}
+			}
+		}
+	}
+
+	ctx := l.ctx.Open(context.Annotation{Key: "external_cpi.correlation", Value: msg.Correlation})
+	ctx.Set("tracing.span", sp)
+
+	return l.handleDeferredCPILogs(msg.Correlation)
+}
+
+// cpiResultCIDTags are the tags for the CIDs of what CPI methods create.
+var cpiResultCIDTags = map[string]string{
+	"create_vm":       "cpi.vm_cid",
+	"create_disk":     "cpi.disk_cid",
+	"create_stemcell": "cpi.stemcell_cid",
+	"snapshot_disk":   "cpi.snapshot_cid",
+}
+
+func (l *Observer) externalCPIResponse(msg taskdebug.ExternalCPIResponseMessage) error {
+	scope := l.ctx.Open(context.Annotation{Key: "external_cpi.correlation", Value: msg.Correlation})
+	spU, ok := scope.Get("tracing.span")
+	if !ok {
+		return observer.InconsistencyError{Expected: "external cpi request span"}
+	}
+
+	sp := spU.(*trace.Span)
+
+	if msg.ErrorType != "" {
+		fields := []trace.Tag{
+			{Key: "cpi.error.ok_to_retry", Value: msg.ErrorOkToRetry},
+		}
+
+		if msg.Stderr != "" {
+			fields = append(fields, trace.Tag{Key: "cpi.stderr", Value: msg.Stderr})
+		}
+
+		sp.SetTag("cpi.error.type", msg.ErrorType)
+		sp.SetError(msg.LogTime, msg.ErrorType, msg.ErrorMessage, fields...)
+	} else if method, ok := sp.GetTag("cpi.method"); ok {
+		if tag, ok := cpiResultCIDTags[method.(string)]; ok {
+			if cid, ok := msg.GetResultCID(); ok {
+				sp.SetTag(tag, cid)
+			}
+		}
+	}
+
+	l.addSpanLogReference(sp, "finish", msg)
+	l.finishSpan(sp, msg.LogTime)
+
+	return nil
+}
+
+func (l *Observer) cpiAWSRPC(msg taskdebug.CPIAWSRPCMessage) error {
+	sp := l.startSpan(
+		"aws",
+		msg.PayloadMethod,
+		l.findParentSpan(context.Annotations{{Key: "external_cpi.correlation", Value: msg.Correlation}}),
+		msg.LogTime.Add(-1*msg.Duration),
+		trace.Tag{Key: "aws.method", Value: msg.PayloadMethod},
+		trace.Tag{Key: "http.status_code", Value: msg.StatusCode},
+		trace.Tag{Key: "aws.retries", Value: msg.Retries},
+	)
+
+	l.addSpanLogReference(sp, "finish", msg)
+	l.finishSpan(sp, msg.LogTime)
+
+	return nil
+}
+
+func (l *Observer) artifactStep(msg taskdebug.ArtifactStepMessage) error {
+	if l.rootSpan == nil {
+		return nil
+	}
+
+	if msg.Step == "downloading" {
+		l.rootSpan.SetTag(fmt.Sprintf("%s.url", msg.Artifact), msg.Detail)
+	}
+
+	return nil
+}
+
+func (l *Observer) releasePackage(msg taskdebug.ReleasePackageMessage) error {
+	l.finishReleasePackage(msg.RawMessage)
+
+	tags := []trace.Tag{
+		{Key: "package_name", Value: msg.PackageName},
+		{Key: "package_version", Value: msg.PackageVersion},
+	}
+
+	if msg.Stemcell != "" {
+		tags = append(tags, trace.Tag{Key: "stemcell", Value: msg.Stemcell})
+	}
+
+	sp := l.startSpan(
+		"release",
+		fmt.Sprintf("%s package: %s", msg.Event, msg.PackageName),
+		l.findParentSpan(),
+		msg.LogTime,
+		tags...,
+	)
+
+	l.addSpanLogReference(sp, "start", msg)
+
+	l.releasePackageSpan = sp
+
+	return nil
+}
+
+func (l *Observer) finishReleasePackage(msg taskdebug.RawMessage) {
+	if l.releasePackageSpan == nil {
+		return
+	}
+
+	l.addSpanLogReference(l.releasePackageSpan, "finish", msg)
+	l.finishSpan(l.releasePackageSpan, msg.LogTime)
+
+	l.releasePackageSpan = nil
+}
+
+func (l *Observer) blobstore(msg taskdebug.BlobstoreMessage) error {
+	if msg.Event != "finish" {
+		// the finishing message includes the duration, so there is nothing to
+		// correlate from the start
+		return nil
+	}
+
+	parentSpan := l.releasePackageSpan
+	if parentSpan == nil {
+		parentSpan = l.findParentSpan(l.getDefaultAnnotations(msg.RawMessage)...)
+	}
+
+	sp := l.startSpan(
+		"blobstore",
+		msg.Action,
+		parentSpan,
+		msg.LogTime.Add(-1*msg.Duration),
+		trace.Tag{Key: "blobstore.action", Value: msg.Action},
+		trace.Tag{Key: "blobstore.object_id", Value: msg.ObjectID},
+	)
+
+	l.addSpanLogReference(sp, "finish", msg)
+	l.finishSpan(sp, msg.LogTime)
+
+	return nil
+}
+
+var lockOperationMap = map[string]string{
+	"Acquiring": "acquire",
+	"Acquired":  "acquired",
+	"Renewing":  "renew",
+	"Deleted":   "delete",
+}
+
+func (l *Observer) lock(msg taskdebug.LockMessage) error {
+	if msg.Event == "Acquiring" {
+		sp := l.startSpan(
+			"lock",
+			strings.TrimPrefix(msg.Name, "lock:"),
+			l.findParentSpan(l.getDefaultAnnotations(msg.RawMessage)...),
+			msg.LogTime,
+		)
+
+		l.addSpanLogReference(sp, "start", msg)
+
+		ctx := l.ctx.Open(context.Annotation{Key: "lock.name", Value: msg.Name})
+		ctx.Set("tracing.span", sp)
+
+		return nil
+	} else if msg.Event == "Acquired" || msg.Event == "Renewing" {
+		// not actually a span?
+		sp := l.startSpan(
+			"lock",
+			lockOperationMap[msg.Event],
+			l.findParentSpan(context.Annotations{{Key: "lock.name", Value: msg.Name}}),
+			msg.LogTime,
+		)
+
+		l.addSpanLogReference(sp, "finish", msg)
+		l.finishSpan(sp, msg.LogTime)
+	} else if msg.Event == "Deleted" {
+		scope := l.ctx.Open(context.Annotation{Key: "lock.name", Value: msg.Name})
+		parentSpanU, ok := scope.Get("tracing.span")
+		if !ok {
+			return observer.InconsistencyError{Expected: "acquiring lock span"}
+		}
+
+		parentSpan := parentSpanU.(*trace.Span)
+
+		// not actually a span?
+		sp := l.startSpan(
+			"lock",
+			lockOperationMap[msg.Event],
+			parentSpan,
+			msg.LogTime,
+		)
+
+		l.addSpanLogReference(sp, "finish", msg)
+		l.finishSpan(sp, msg.LogTime)
+
+		l.addSpanLogReference(parentSpan, "finish", msg)
+		l.finishSpan(parentSpan, msg.LogTime)
+	} else {
+		return fmt.Errorf("unexpected lock event: %s", msg.Event)
+	}
+
+	return nil
+}
+
+func (l *Observer) addSpanLogReference(sp *trace.Span, event string, msg log.Line) {
+	if !l.includeLogReferences {
+		return
+	}
+
+	fields := []trace.Tag{
+		{Key: "line", Value: msg.LineOffset()},
+		{Key: "message", Value: msg.LineData()},
+	}
+
+	if end := msg.LineEndOffset(); end != msg.LineOffset() {
+		fields = append(fields, trace.Tag{Key: "line.end", Value: end})
+	}
+
+	if source := msg.LineSource(); source != "" {
+		fields = append(fields, trace.Tag{Key: "source", Value: source})
+	}
+
+	logTime := l.lastMessage.LogTime
+
+	if getter, ok := msg.(taskdebug.RawMessageGetter); ok && !getter.GetRawMessage().LogTime.IsZero() {
+		logTime = getter.GetRawMessage().LogTime
+	} else if event, ok := msg.(taskevent.EventMessage); ok {
+		logTime = event.Time
+	} else if event, ok := msg.(taskevent.ErrorMessage); ok {
+		logTime = event.Time
+	}
+
+	sp.AddEvent(logTime, event, fields...)
+}
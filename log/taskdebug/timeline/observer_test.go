@@ -0,0 +1,231 @@
+package timeline
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dpb587/bosh-log-tracer/log/input"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug/parser"
+	"github.com/dpb587/bosh-log-tracer/observer/context"
+	"github.com/dpb587/bosh-log-tracer/pipeline"
+	"github.com/dpb587/bosh-log-tracer/trace"
+)
+
+var update = flag.Bool("update", false, "update the golden files of testdata")
+
+func TestObserverGolden(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("..", "testdata", "*.log"))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(paths) == 0 {
+		t.Fatal("expected fixtures")
+	}
+
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".log")
+
+		t.Run(name, func(t *testing.T) {
+			obs := NewObserver(&context.Context{}, ObserverOptions{
+				IncludeLogReferences: true,
+			})
+
+			err := pipeline.Run(input.NewFileInput(path), parser.NewParser(parser.Options{}), obs)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			actual := renderTree(obs.Trace())
+			goldenPath := filepath.Join("testdata", name+".golden")
+
+			if *update {
+				err := ioutil.WriteFile(goldenPath, actual, 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			expected, err := ioutil.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("reading golden file (use -update to create it): %s", err)
+			}
+
+			if !bytes.Equal(actual, expected) {
+				t.Errorf("trace does not match %s (use -update to accept it)\nexpected:\n%s\nactual:\n%s", goldenPath, expected, actual)
+			}
+		})
+	}
+}
+
+func TestObserverGoldenConcurrently(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("..", "testdata", "*.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".log")
+
+		t.Run(name, func(t *testing.T) {
+			obs := NewObserver(&context.Context{}, ObserverOptions{
+				IncludeLogReferences: true,
+			})
+
+			err := pipeline.RunConcurrently(input.NewFileInput(path), parser.NewParser(parser.Options{}), obs, 4)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			goldenPath := filepath.Join("testdata", name+".golden")
+
+			expected, err := ioutil.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("reading golden file: %s", err)
+			}
+
+			if actual := renderTree(obs.Trace()); !bytes.Equal(actual, expected) {
+				t.Errorf("trace does not match %s\nexpected:\n%s\nactual:\n%s", goldenPath, expected, actual)
+			}
+		})
+	}
+}
+
+// TestObserverGoldenTaskLogs traces fixtures which have another log of their
+// task read before their debug log, like a task directory.
+func TestObserverGoldenTaskLogs(t *testing.T) {
+	for _, output := range []struct {
+		ext    string
+		golden string
+	}{
+		{ext: ".event", golden: ".events.golden"},
+		{ext: ".cpi", golden: ".cpi.golden"},
+	} {
+		paths, err := filepath.Glob(filepath.Join("..", "testdata", "*"+output.ext))
+		if err != nil {
+			t.Fatal(err)
+		} else if len(paths) == 0 {
+			t.Fatalf("expected %s fixtures", output.ext)
+		}
+
+		for _, path := range paths {
+			name := strings.TrimSuffix(filepath.Base(path), output.ext)
+
+			t.Run(name+output.ext, func(t *testing.T) {
+				obs := NewObserver(&context.Context{}, ObserverOptions{
+					IncludeLogReferences: true,
+				})
+
+				in := input.Concat(input.NewFileInput(path), input.NewFileInput(strings.TrimSuffix(path, output.ext)+".log"))
+
+				err := pipeline.RunConcurrently(in, parser.NewParser(parser.Options{}), obs, 4)
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+
+				actual := renderTree(obs.Trace())
+				goldenPath := filepath.Join("testdata", name+output.golden)
+
+				if *update {
+					err := ioutil.WriteFile(goldenPath, actual, 0644)
+					if err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				expected, err := ioutil.ReadFile(goldenPath)
+				if err != nil {
+					t.Fatalf("reading golden file (use -update to create it): %s", err)
+				}
+
+				if !bytes.Equal(actual, expected) {
+					t.Errorf("trace does not match %s (use -update to accept it)\nexpected:\n%s\nactual:\n%s", goldenPath, expected, actual)
+				}
+			})
+		}
+	}
+}
+
+// renderTree writes the spans of a trace as an indented tree. IDs are left out
+// and times are relative to the start of the trace so the result is stable.
+func renderTree(tr *trace.Trace) []byte {
+	buf := &bytes.Buffer{}
+
+	children := map[trace.SpanID][]*trace.Span{}
+	known := map[trace.SpanID]bool{}
+
+	var start time.Time
+
+	for idx, sp := range tr.Spans {
+		known[sp.ID] = true
+
+		if idx == 0 || sp.StartTime.Before(start) {
+			start = sp.StartTime
+		}
+	}
+
+	for _, sp := range tr.Spans {
+		parent := sp.ParentID
+		if !known[parent] {
+			parent = 0
+		}
+
+		children[parent] = append(children[parent], sp)
+	}
+
+	var walk func(parent trace.SpanID, depth int)
+
+	walk = func(parent trace.SpanID, depth int) {
+		for _, sp := range children[parent] {
+			indent := strings.Repeat("  ", depth)
+
+			fmt.Fprintf(buf, "%s%s: %s (+%s, %s)\n", indent, sp.Service, sp.OperationName, sp.StartTime.Sub(start), sp.Duration())
+
+			if sp.IsError() {
+				fmt.Fprintf(buf, "%s  ! %s\n", indent, sp.Status.Message)
+			}
+
+			tags := append([]trace.Tag(nil), sp.Tags...)
+			sort.SliceStable(tags, func(i, j int) bool {
+				return tags[i].Key < tags[j].Key
+			})
+
+			for _, tag := range tags {
+				fmt.Fprintf(buf, "%s  %s = %v\n", indent, tag.Key, tag.ScalarValue())
+			}
+
+			for _, event := range sp.Events {
+				line := "-"
+				var stack interface{}
+
+				for _, field := range event.Fields {
+					switch field.Key {
+					case "line":
+						line = fmt.Sprintf("%v", field.ScalarValue())
+					case "line.end":
+						line += fmt.Sprintf("-%v", field.ScalarValue())
+					case "stack":
+						stack = field.ScalarValue()
+					}
+				}
+
+				fmt.Fprintf(buf, "%s  @ +%s %s (line %s)\n", indent, event.Time.Sub(start), event.Name, line)
+
+				if stack != nil {
+					fmt.Fprintf(buf, "%s    %s\n", indent, strings.Replace(fmt.Sprintf("%v", stack), "\n", "\n"+indent+"    ", -1))
+				}
+			}
+
+			walk(sp.ID, depth+1)
+		}
+	}
+
+	walk(0, 0)
+
+	return buf.Bytes()
+}
@@ -0,0 +1,172 @@
+package timeline
+
+import (
+	"strings"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/taskdebug"
+)
+
+// stageModel describes the stages a type of task goes through, emulated by
+// watching for well-known messages since the debug log does not record them.
+type stageModel struct {
+	Operation string
+	Triggers  []stageTrigger
+}
+
+type stageTrigger struct {
+	Stage string
+
+	// any one of these message conditions starts the stage
+	Message        string
+	MessagePrefix  string
+	AgentMethod    string
+	CPIMethod      string
+	ArtifactStep   string
+	ReleasePackage bool
+
+	// only trigger when currently in this stage
+	During string
+}
+
+func (t stageTrigger) matches(currentStage string, msg log.Line) (taskdebug.RawMessage, bool) {
+	if t.During != "" && t.During != currentStage {
+		return taskdebug.RawMessage{}, false
+	}
+
+	switch m := msg.(type) {
+	case taskdebug.RawMessage:
+		if t.Message != "" && m.Message == t.Message {
+			return m, true
+		} else if t.MessagePrefix != "" && strings.HasPrefix(m.Message, t.MessagePrefix) {
+			return m, true
+		}
+	case taskdebug.NATSMessageSentAgentMessage:
+		if t.AgentMethod != "" && m.PayloadMethod == t.AgentMethod {
+			return m.RawMessage, true
+		}
+	case taskdebug.ExternalCPIRequestMessage:
+		if t.CPIMethod != "" && m.PayloadMethod == t.CPIMethod {
+			return m.RawMessage, true
+		}
+	case taskdebug.ArtifactStepMessage:
+		if t.ArtifactStep != "" && m.Step == t.ArtifactStep {
+			return m.RawMessage, true
+		}
+	case taskdebug.ReleasePackageMessage:
+		if t.ReleasePackage {
+			return m.RawMessage, true
+		}
+	}
+
+	return taskdebug.RawMessage{}, false
+}
+
+var deployStageTriggers = []stageTrigger{
+	{Stage: "preparing", Message: "Creating deployment plan"},
+	{Stage: "compilation", Message: "Generating a list of compile tasks"},
+	{Stage: "updating", Message: "Updating deployment"},
+	// seems this is actually logged twice; finishing is kinda implied
+	{Stage: "finishing", Message: "Finished updating deployment", During: "updating"},
+}
+
+var deployStageModel = stageModel{
+	Operation: "update_deployment",
+	Triggers:  deployStageTriggers,
+}
+
+// stop, start, restart and recreate are all driven through a deployment update
+// with different instance states, so they go through the same stages
+var instanceStateStageModels = map[string]stageModel{
+	"recreate": {Operation: "recreate", Triggers: deployStageTriggers},
+	"restart":  {Operation: "restart", Triggers: deployStageTriggers},
+	"start":    {Operation: "start", Triggers: deployStageTriggers},
+	"stop":     {Operation: "stop", Triggers: deployStageTriggers},
+}
+
+var deleteDeploymentStageModel = stageModel{
+	Operation: "delete_deployment",
+	Triggers: []stageTrigger{
+		{Stage: "deleting_instances", MessagePrefix: "Deleting instances"},
+		{Stage: "deleting_instances", MessagePrefix: "Deleting instance '"},
+		{Stage: "removing_artifacts", MessagePrefix: "Detaching stemcells"},
+		{Stage: "removing_artifacts", MessagePrefix: "Removing deployment artifacts"},
+		{Stage: "destroying", MessagePrefix: "Destroying deployment"},
+	},
+}
+
+var cloudCheckStageModel = stageModel{
+	Operation: "cloud_check",
+	Triggers: []stageTrigger{
+		{Stage: "scanning", MessagePrefix: "Scanning "},
+		{Stage: "scanning", MessagePrefix: "Started scanning "},
+		{Stage: "applying_resolutions", MessagePrefix: "Applying problem resolutions"},
+		{Stage: "applying_resolutions", MessagePrefix: "Resolving problem"},
+	},
+}
+
+var runErrandStageModel = stageModel{
+	Operation: "run_errand",
+	Triggers: []stageTrigger{
+		{Stage: "preparing", Message: "Creating deployment plan"},
+		{Stage: "compilation", Message: "Generating a list of compile tasks"},
+		{Stage: "updating", Message: "Updating deployment"},
+		{Stage: "running", AgentMethod: "run_errand"},
+		{Stage: "fetching_logs", AgentMethod: "fetch_logs"},
+		{Stage: "cleaning_up", MessagePrefix: "Deleting errand instance"},
+		{Stage: "cleaning_up", AgentMethod: "stop", During: "fetching_logs"},
+		{Stage: "cleaning_up", AgentMethod: "stop", During: "running"},
+	},
+}
+
+var uploadReleaseStageModel = stageModel{
+	Operation: "upload_release",
+	Triggers: []stageTrigger{
+		{Stage: "downloading", ArtifactStep: "downloading"},
+		{Stage: "extracting", ArtifactStep: "extracting"},
+		{Stage: "verifying", ArtifactStep: "verifying"},
+		{Stage: "checking", ArtifactStep: "checking"},
+		{Stage: "packages", ReleasePackage: true},
+		{Stage: "saving", ArtifactStep: "saving"},
+	},
+}
+
+var uploadStemcellStageModel = stageModel{
+	Operation: "upload_stemcell",
+	Triggers: []stageTrigger{
+		{Stage: "downloading", ArtifactStep: "downloading"},
+		{Stage: "extracting", ArtifactStep: "extracting"},
+		{Stage: "verifying", ArtifactStep: "verifying"},
+		{Stage: "checking", ArtifactStep: "checking"},
+		{Stage: "uploading", ArtifactStep: "uploading"},
+		{Stage: "uploading", CPIMethod: "create_stemcell"},
+		{Stage: "saving", ArtifactStep: "saving"},
+	},
+}
+
+// getStageModel picks the stages for a task based on the job type and
+// description of its task record.
+func getStageModel(taskType, description string) stageModel {
+	switch taskType {
+	case "update_deployment":
+		verb := strings.SplitN(description, " ", 2)[0]
+
+		if model, found := instanceStateStageModels[verb]; found {
+			return model
+		}
+
+		return deployStageModel
+	case "delete_deployment":
+		return deleteDeploymentStageModel
+	case "cck_scan", "cck_apply", "cck_scan_and_fix":
+		return cloudCheckStageModel
+	case "run_errand":
+		return runErrandStageModel
+	case "update_release":
+		return uploadReleaseStageModel
+	case "update_stemcell":
+		return uploadStemcellStageModel
+	}
+
+	return stageModel{Operation: taskType}
+}
@@ -0,0 +1,18 @@
+package taskevent
+
+import (
+	"time"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+)
+
+// ErrorMessage is the error which failed the task as a whole.
+type ErrorMessage struct {
+	log.RawLine
+
+	Time    time.Time
+	Code    int
+	Message string
+}
+
+var _ log.Line = &ErrorMessage{}
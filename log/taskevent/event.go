@@ -0,0 +1,43 @@
+package taskevent
+
+import (
+	"time"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+)
+
+// EventMessage is the progress of one of the tasks of a stage, such as a
+// package during "Compiling packages" or an instance during "Updating
+// instance".
+type EventMessage struct {
+	log.RawLine
+
+	Time  time.Time
+	Stage string
+	Tags  []string
+
+	Task  string
+	Index int
+	Total int
+
+	// State is one of started, in_progress, finished or failed.
+	State    string
+	Progress int
+
+	// Error is the reason of a failed task.
+	Error string
+}
+
+var _ log.Line = &EventMessage{}
+
+// StageKey identifies the stage of the event; a stage is repeated with
+// different tags (e.g. "Updating instance" for every instance group).
+func (m EventMessage) StageKey() string {
+	key := m.Stage
+
+	for _, tag := range m.Tags {
+		key += "/" + tag
+	}
+
+	return key
+}
@@ -0,0 +1,87 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/taskevent"
+)
+
+var Parser = parser{}
+
+type parser struct{}
+
+// {"time":1560908692,"stage":"Preparing deployment","tags":[],"total":1,"task":"Preparing deployment","index":1,"state":"started","progress":0}
+// {"time":1560908698,"error":{"code":400007,"message":"..."}}
+type event struct {
+	Time     int64    `json:"time"`
+	Stage    string   `json:"stage"`
+	Tags     []string `json:"tags"`
+	Total    int      `json:"total"`
+	Task     string   `json:"task"`
+	Index    int      `json:"index"`
+	State    string   `json:"state"`
+	Progress int      `json:"progress"`
+	Data     struct {
+		Error string `json:"error"`
+	} `json:"data"`
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// IsEvent reports whether data is a line of an event log rather than a debug
+// log.
+func IsEvent(data string) bool {
+	return strings.HasPrefix(data, `{"time":`)
+}
+
+func (p parser) Parse(in log.Line) (log.Line, error) {
+	var e event
+
+	err := json.Unmarshal([]byte(in.LineData()), &e)
+	if err != nil {
+		return nil, fmt.Errorf("parsing event: %s", err)
+	}
+
+	raw := log.RawLine{
+		RawLineSource: in.LineSource(),
+		RawLineOffset: in.LineOffset(),
+		RawLineData:   in.LineData(),
+	}
+
+	if end := in.LineEndOffset(); end > raw.RawLineOffset {
+		raw.RawLineEndOffset = end
+	}
+
+	t := time.Unix(e.Time, 0).UTC()
+
+	if e.Error != nil {
+		return taskevent.ErrorMessage{
+			RawLine: raw,
+			Time:    t,
+			Code:    e.Error.Code,
+			Message: e.Error.Message,
+		}, nil
+	} else if e.Stage == "" {
+		// other events (e.g. deprecation warnings) are not about progress
+		return in, nil
+	}
+
+	return taskevent.EventMessage{
+		RawLine:  raw,
+		Time:     t,
+		Stage:    e.Stage,
+		Tags:     e.Tags,
+		Task:     e.Task,
+		Index:    e.Index,
+		Total:    e.Total,
+		State:    e.State,
+		Progress: e.Progress,
+		Error:    e.Data.Error,
+	}, nil
+}
@@ -0,0 +1,106 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/taskevent"
+)
+
+func TestParser(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		expected func(raw log.RawLine) log.Line
+		err      bool
+	}{
+		{
+			name: "started",
+			line: `{"time":1560908693,"stage":"Compiling packages","tags":[],"total":2,"task":"golang-1.12-linux/8a1f2a8c0a3d4b5e9d3c2e4b1f7a6c5d8e9f0a1b","index":1,"state":"started","progress":0}`,
+			expected: func(raw log.RawLine) log.Line {
+				return taskevent.EventMessage{
+					RawLine:  raw,
+					Time:     time.Date(2019, time.June, 19, 1, 44, 53, 0, time.UTC),
+					Stage:    "Compiling packages",
+					Tags:     []string{},
+					Task:     "golang-1.12-linux/8a1f2a8c0a3d4b5e9d3c2e4b1f7a6c5d8e9f0a1b",
+					Index:    1,
+					Total:    2,
+					State:    "started",
+					Progress: 0,
+				}
+			},
+		},
+		{
+			name: "failed",
+			line: `{"time":1560915898,"stage":"Updating instance","tags":["web"],"total":1,"task":"web/d309fb56-9c0c-0e02-7f8e-d14dbd6f186d (0) (canary)","index":1,"state":"failed","progress":100,"data":{"error":"'web/d309fb56-9c0c-0e02-7f8e-d14dbd6f186d (0)' is not running after update."}}`,
+			expected: func(raw log.RawLine) log.Line {
+				return taskevent.EventMessage{
+					RawLine:  raw,
+					Time:     time.Date(2019, time.June, 19, 3, 44, 58, 0, time.UTC),
+					Stage:    "Updating instance",
+					Tags:     []string{"web"},
+					Task:     "web/d309fb56-9c0c-0e02-7f8e-d14dbd6f186d (0) (canary)",
+					Index:    1,
+					Total:    1,
+					State:    "failed",
+					Progress: 100,
+					Error:    "'web/d309fb56-9c0c-0e02-7f8e-d14dbd6f186d (0)' is not running after update.",
+				}
+			},
+		},
+		{
+			name: "error",
+			line: `{"time":1560915898,"error":{"code":400007,"message":"'web/0' is not running after update."}}`,
+			expected: func(raw log.RawLine) log.Line {
+				return taskevent.ErrorMessage{
+					RawLine: raw,
+					Time:    time.Date(2019, time.June, 19, 3, 44, 58, 0, time.UTC),
+					Code:    400007,
+					Message: "'web/0' is not running after update.",
+				}
+			},
+		},
+		{
+			name: "other event",
+			line: `{"time":1560908692,"type":"deprecation","message":"Ignoring cloud config. Manifest contains 'networks' section."}`,
+		},
+		{
+			name: "invalid",
+			line: `{"time":1560908692,"stage":`,
+			err:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := log.RawLine{RawLineSource: "event", RawLineOffset: 1, RawLineData: tt.line}
+
+			if !IsEvent(tt.line) {
+				t.Fatal("expected an event")
+			}
+
+			actual, err := Parser.Parse(raw)
+			if tt.err {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+
+				return
+			} else if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			var expected log.Line = raw
+			if tt.expected != nil {
+				expected = tt.expected(raw)
+			}
+
+			if !reflect.DeepEqual(actual, expected) {
+				t.Fatalf("expected %#+v, but got %#+v", expected, actual)
+			}
+		})
+	}
+}
@@ -6,14 +6,19 @@ import (
 	"github.com/dpb587/bosh-log-tracer/log"
 	"github.com/dpb587/bosh-log-tracer/log/taskdebug"
 	"github.com/dpb587/bosh-log-tracer/observer"
+	"github.com/dpb587/bosh-log-tracer/redact"
 )
 
-type Observer struct{}
+type Observer struct {
+	redactor *redact.Redactor
+}
 
 var _ observer.Observer = &Observer{}
 
-func NewObserver() *Observer {
-	return &Observer{}
+func NewObserver(redactor *redact.Redactor) *Observer {
+	return &Observer{
+		redactor: redactor,
+	}
 }
 
 func (l *Observer) Begin() error {
@@ -24,18 +29,24 @@ func (l *Observer) Commit() error {
 	return nil
 }
 
+func (l *Observer) Flush() error {
+	return nil
+}
+
 func (l *Observer) Handle(msg log.Line) error {
 	switch m := msg.(type) {
 	case taskdebug.InstanceAspectChangedMessage:
 	case taskdebug.ExternalCPIMessage, taskdebug.ExternalCPIRequestMessage:
 		return l.print(m)
+	case taskdebug.ErrorMessage, taskdebug.TaskStateMessage:
+		return l.print(m)
 	}
 
 	return nil
 }
 
 func (l *Observer) print(msg log.Line) error {
-	fmt.Printf("%#+v\n", msg)
+	fmt.Printf("%#+v\n", l.redactor.Value(msg))
 
 	return nil
 }
@@ -0,0 +1,15 @@
+package observer
+
+import "fmt"
+
+// InconsistencyError indicates a line did not fit the observer's model of the
+// task, typically because an earlier, related line was never seen.
+type InconsistencyError struct {
+	Expected string
+}
+
+var _ error = InconsistencyError{}
+
+func (e InconsistencyError) Error() string {
+	return fmt.Sprintf("logical inconsistency: expected %s", e.Expected)
+}
@@ -6,4 +6,8 @@ type Observer interface {
 	Begin() error
 	Commit() error
 	Handle(log.Line) error
+
+	// Flush is called periodically while an input is still being written so
+	// observers may report their progress before it is committed.
+	Flush() error
 }
@@ -0,0 +1,40 @@
+package observer
+
+import "github.com/dpb587/bosh-log-tracer/log"
+
+type lenientObserver struct {
+	observer    Observer
+	diagnostics *log.Diagnostics
+}
+
+var _ Observer = &lenientObserver{}
+
+// NewLenientObserver records errors of handling individual lines as
+// diagnostics rather than failing.
+func NewLenientObserver(observer Observer, diagnostics *log.Diagnostics) Observer {
+	return &lenientObserver{
+		observer:    observer,
+		diagnostics: diagnostics,
+	}
+}
+
+func (o lenientObserver) Begin() error {
+	return o.observer.Begin()
+}
+
+func (o lenientObserver) Commit() error {
+	return o.observer.Commit()
+}
+
+func (o lenientObserver) Flush() error {
+	return o.observer.Flush()
+}
+
+func (o lenientObserver) Handle(l log.Line) error {
+	err := o.observer.Handle(l)
+	if err != nil {
+		o.diagnostics.Add(log.NewLineError(l, err))
+	}
+
+	return nil
+}
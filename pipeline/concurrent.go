@@ -0,0 +1,227 @@
+package pipeline
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/input"
+	"github.com/dpb587/bosh-log-tracer/observer"
+)
+
+// pendingPerWorker bounds how many lines may be read ahead of the observer.
+const pendingPerWorker = 64
+
+// RunConcurrently is like Run, but lines are parsed by several workers while
+// the observer still receives them one at a time and in the order of the
+// input. A single worker is the same as Run.
+func RunConcurrently(in input.Input, lineParser log.LineParser, obs observer.Observer, workers int) error {
+	return RunConcurrentlyContext(context.Background(), in, lineParser, obs, workers)
+}
+
+// RunConcurrentlyContext is like RunConcurrently, but stops reading and parsing
+// once ctx is done. The observer is still committed with the lines it has seen.
+func RunConcurrentlyContext(ctx context.Context, in input.Input, lineParser log.LineParser, obs observer.Observer, workers int) error {
+	if workers < 2 && ctx.Done() == nil {
+		return Run(in, lineParser, obs)
+	}
+
+	return runObserved(ctx, in, lineParser, obs, runOptions{workers: workers})
+}
+
+type runOptions struct {
+	workers int
+
+	// flushInterval is how often the observer is flushed, if at all.
+	flushInterval time.Duration
+
+	// follow, if set, keeps reading the input past its end until it is done.
+	follow context.Context
+}
+
+// runObserved begins and commits the observer around runConcurrently.
+func runObserved(ctx context.Context, in input.Input, lineParser log.LineParser, obs observer.Observer, opts runOptions) error {
+	if opts.workers < 1 {
+		opts.workers = 1
+	}
+
+	err := obs.Begin()
+	if err != nil {
+		return err
+	}
+
+	runErr := runConcurrently(ctx, in, lineParser, obs, opts)
+
+	err = obs.Commit()
+	if runErr != nil {
+		return runErr
+	}
+
+	return err
+}
+
+type parsedLine struct {
+	seq  int
+	line log.Line
+	err  error
+}
+
+func runConcurrently(ctx context.Context, in input.Input, lineParser log.LineParser, obs observer.Observer, opts runOptions) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	parseConcurrently, parseOrdered, drain := splitParser(lineParser)
+
+	// a slot is taken for every line read and released once it was observed,
+	// which bounds the lines waiting for an earlier one to be parsed
+	slots := make(chan struct{}, opts.workers*pendingPerWorker)
+	lines := make(chan parsedLine, opts.workers)
+	parsed := make(chan parsedLine, opts.workers)
+
+	var scanErr error
+
+	scanned := make(chan struct{})
+
+	go func() {
+		defer close(scanned)
+		defer close(lines)
+
+		var seq int
+
+		scanErr = scan(in, lineParser, opts.follow, func(l log.Line) error {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+
+			select {
+			case lines <- parsedLine{seq: seq, line: l}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+
+			seq++
+
+			return nil
+		})
+	}()
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < opts.workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for l := range lines {
+				l.line, l.err = parseConcurrently(l.line)
+
+				select {
+				case parsed <- l:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(parsed)
+	}()
+
+	handle := func(l parsedLine) error {
+		if l.err != nil {
+			return l.err
+		}
+
+		lines, err := parseOrdered(l.line)
+		if err != nil {
+			return err
+		}
+
+		return observe(obs, lines)
+	}
+
+	// lines are parsed out of order; hold them until all earlier lines were
+	// handled
+	pending := map[int]parsedLine{}
+	var next int
+	var err error
+
+	var flush <-chan time.Time
+
+	if opts.flushInterval > 0 {
+		ticker := time.NewTicker(opts.flushInterval)
+		defer ticker.Stop()
+
+		flush = ticker.C
+	}
+
+observe:
+	for {
+		select {
+		case l, ok := <-parsed:
+			if !ok {
+				break observe
+			} else if err != nil {
+				// draining until the workers noticed the cancellation
+				continue
+			}
+
+			pending[l.seq] = l
+
+			for {
+				l, found := pending[next]
+				if !found {
+					break
+				}
+
+				delete(pending, next)
+				next++
+				<-slots
+
+				err = handle(l)
+				if err != nil {
+					cancel()
+
+					break
+				}
+			}
+		case <-flush:
+			if err != nil {
+				continue
+			}
+
+			err = obs.Flush()
+			if err != nil {
+				cancel()
+			}
+		}
+	}
+
+	<-scanned
+
+	if err == nil {
+		// lines held by the parser were still seen
+		var lines []log.Line
+
+		lines, err = drain()
+		if err == nil {
+			err = observe(obs, lines)
+		}
+	}
+
+	if err != nil {
+		return err
+	} else if scanErr != nil {
+		return scanErr
+	}
+
+	// workers may have dropped lines once ctx was done, even if the input had
+	// already been read
+	return ctx.Err()
+}
@@ -0,0 +1,234 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/input"
+)
+
+func writeInput(t *testing.T, lines int) input.Input {
+	t.Helper()
+
+	var data []string
+
+	for i := 1; i <= lines; i++ {
+		data = append(data, fmt.Sprintf("line %d", i))
+	}
+
+	path := filepath.Join(t.TempDir(), "test.log")
+
+	err := ioutil.WriteFile(path, []byte(strings.Join(data, "\n")+"\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return input.NewFileInput(path)
+}
+
+// parserFunc parses lines with a function after a random delay so lines finish
+// out of order.
+type parserFunc func(log.Line) (log.Line, error)
+
+func (p parserFunc) Parse(l log.Line) (log.Line, error) {
+	time.Sleep(time.Duration(rand.Intn(100)) * time.Microsecond)
+
+	return p(l)
+}
+
+var passthrough = parserFunc(func(l log.Line) (log.Line, error) {
+	return l, nil
+})
+
+// orderedParser records the lines it completes, which must be in order.
+type orderedParser struct {
+	parserFunc
+
+	ordered []int64
+}
+
+func (p *orderedParser) ParseConcurrently(l log.Line) (log.Line, error) {
+	return p.Parse(l)
+}
+
+func (p *orderedParser) ParseOrdered(l log.Line) (log.Line, error) {
+	p.ordered = append(p.ordered, l.LineOffset())
+
+	return l, nil
+}
+
+type recordingObserver struct {
+	lines     []int64
+	flushed   int
+	committed bool
+
+	handle func(log.Line) error
+}
+
+func (o *recordingObserver) Begin() error {
+	return nil
+}
+
+func (o *recordingObserver) Handle(l log.Line) error {
+	o.lines = append(o.lines, l.LineOffset())
+
+	if o.handle != nil {
+		return o.handle(l)
+	}
+
+	return nil
+}
+
+func (o *recordingObserver) Flush() error {
+	o.flushed++
+
+	return nil
+}
+
+func (o *recordingObserver) Commit() error {
+	o.committed = true
+
+	return nil
+}
+
+func expectOffsets(t *testing.T, actual []int64, count int) {
+	t.Helper()
+
+	if len(actual) != count {
+		t.Fatalf("expected %d lines, but got %d", count, len(actual))
+	}
+
+	for idx, offset := range actual {
+		if offset != int64(idx+1) {
+			t.Fatalf("expected line %d at %d, but got line %d", idx+1, idx, offset)
+		}
+	}
+}
+
+func TestRunConcurrently(t *testing.T) {
+	obs := &recordingObserver{}
+	p := &orderedParser{parserFunc: passthrough}
+
+	err := RunConcurrently(writeInput(t, 1000), p, obs, 8)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expectOffsets(t, obs.lines, 1000)
+	expectOffsets(t, p.ordered, 1000)
+
+	if !obs.committed {
+		t.Errorf("expected the observer to be committed")
+	}
+}
+
+func TestRunConcurrentlyParseError(t *testing.T) {
+	obs := &recordingObserver{}
+
+	err := RunConcurrently(writeInput(t, 1000), parserFunc(func(l log.Line) (log.Line, error) {
+		if l.LineOffset() == 500 {
+			return nil, errors.New("fake-err")
+		}
+
+		return l, nil
+	}), obs, 8)
+	if err == nil || err.Error() != "fake-err" {
+		t.Fatalf("expected fake-err, but got %v", err)
+	}
+
+	// every line before the failing one is still observed
+	expectOffsets(t, obs.lines, 499)
+
+	if !obs.committed {
+		t.Errorf("expected the observer to be committed")
+	}
+}
+
+func TestRunConcurrentlyObserverError(t *testing.T) {
+	obs := &recordingObserver{
+		handle: func(l log.Line) error {
+			if l.LineOffset() == 10 {
+				return errors.New("fake-err")
+			}
+
+			return nil
+		},
+	}
+
+	err := RunConcurrently(writeInput(t, 1000), passthrough, obs, 8)
+	if err == nil || err.Error() != "fake-err" {
+		t.Fatalf("expected fake-err, but got %v", err)
+	}
+
+	expectOffsets(t, obs.lines, 10)
+}
+
+func TestRunConcurrentlyContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	obs := &recordingObserver{
+		handle: func(l log.Line) error {
+			if l.LineOffset() == 10 {
+				cancel()
+			}
+
+			return nil
+		},
+	}
+
+	err := RunConcurrentlyContext(ctx, writeInput(t, 10000), passthrough, obs, 8)
+	if err != context.Canceled {
+		t.Fatalf("expected the context to be cancelled, but got %v", err)
+	} else if len(obs.lines) == 10000 {
+		t.Errorf("expected processing to stop early")
+	}
+
+	if !obs.committed {
+		t.Errorf("expected the observer to be committed")
+	}
+}
+
+func TestRunConcurrentlyBoundsPending(t *testing.T) {
+	const workers = 4
+
+	var parsed int64
+
+	release := make(chan struct{})
+
+	obs := &recordingObserver{}
+
+	go func() {
+		// the first line is held until the others had plenty of time
+		time.Sleep(100 * time.Millisecond)
+		close(release)
+	}()
+
+	err := RunConcurrently(writeInput(t, 10000), parserFunc(func(l log.Line) (log.Line, error) {
+		if l.LineOffset() == 1 {
+			<-release
+
+			if n := atomic.LoadInt64(&parsed); n > workers*pendingPerWorker {
+				t.Errorf("expected at most %d lines to be parsed ahead, but got %d", workers*pendingPerWorker, n)
+			}
+		}
+
+		atomic.AddInt64(&parsed, 1)
+
+		return l, nil
+	}), obs, workers)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expectOffsets(t, obs.lines, 10000)
+}
@@ -0,0 +1,28 @@
+package pipeline
+
+import (
+	"context"
+	"time"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/input"
+	"github.com/dpb587/bosh-log-tracer/observer"
+)
+
+// followPollInterval is how often a followed input is checked for new lines
+// once its end was reached.
+var followPollInterval = 250 * time.Millisecond
+
+// Follow is like RunConcurrently for an input which is still being written,
+// such as the debug log of a running task. Reading continues past the end of
+// the input until ctx is done, after which the remaining lines are still
+// observed. The observer is flushed every flushInterval in the meantime.
+func Follow(ctx context.Context, in input.Input, lineParser log.LineParser, obs observer.Observer, workers int, flushInterval time.Duration) error {
+	// ctx only stops following; lines which were already written are not
+	// dropped
+	return runObserved(context.Background(), in, lineParser, obs, runOptions{
+		workers:       workers,
+		flushInterval: flushInterval,
+		follow:        ctx,
+	})
+}
@@ -0,0 +1,172 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/input"
+)
+
+func TestFollow(t *testing.T) {
+	defer func(interval time.Duration) {
+		followPollInterval = interval
+	}(followPollInterval)
+
+	followPollInterval = time.Millisecond
+
+	in := writeInput(t, 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	obs := &recordingObserver{
+		handle: func(l log.Line) error {
+			if l.LineOffset() == 15 {
+				// lines written before following stopped are still observed
+				cancel()
+			}
+
+			return nil
+		},
+	}
+
+	done := make(chan error)
+
+	go func() {
+		done <- Follow(ctx, in, &orderedParser{parserFunc: passthrough}, obs, 4, time.Millisecond)
+	}()
+
+	// the input is growing while it is followed
+	time.Sleep(50 * time.Millisecond)
+
+	fh, err := os.OpenFile(in.Name, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer fh.Close()
+
+	var data string
+
+	for i := 11; i <= 20; i++ {
+		data += fmt.Sprintf("line %d\n", i)
+	}
+
+	_, err = fh.WriteString(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected following to stop")
+	}
+
+	expectOffsets(t, obs.lines, 20)
+
+	if obs.flushed == 0 {
+		t.Fatal("expected the observer to be flushed while following")
+	}
+
+	if !obs.committed {
+		t.Fatal("expected the observer to be committed")
+	}
+}
+
+func TestFollowCompressed(t *testing.T) {
+	in := writeInput(t, 1)
+	in.Name += ".gz"
+
+	err := Follow(context.Background(), in, passthrough, &recordingObserver{}, 1, 0)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func appendLines(t *testing.T, in input.Input, from, to int) {
+	t.Helper()
+
+	fh, err := os.OpenFile(in.Name, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer fh.Close()
+
+	for i := from; i <= to; i++ {
+		_, err = fmt.Fprintf(fh, "line %d\n", i)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFollowConcat(t *testing.T) {
+	defer func(interval time.Duration) {
+		followPollInterval = interval
+	}(followPollInterval)
+
+	followPollInterval = time.Millisecond
+
+	first := writeInput(t, 3)
+	last := writeInput(t, 5)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	type seenLine struct {
+		source string
+		offset int64
+	}
+
+	var seen []seenLine
+
+	obs := &recordingObserver{
+		handle: func(l log.Line) error {
+			seen = append(seen, seenLine{source: l.LineSource(), offset: l.LineOffset()})
+
+			if l.LineSource() == last.Name && l.LineOffset() == 5 {
+				// every part keeps growing while it is followed
+				appendLines(t, first, 4, 5)
+				appendLines(t, last, 6, 7)
+			} else if l.LineSource() == last.Name && l.LineOffset() == 7 {
+				cancel()
+			}
+
+			return nil
+		},
+	}
+
+	err := Follow(ctx, input.Concat(first, last), &orderedParser{parserFunc: passthrough}, obs, 4, time.Millisecond)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	offsets := map[string]int64{}
+
+	for idx, l := range seen {
+		offsets[l.source]++
+
+		if l.offset != offsets[l.source] {
+			t.Errorf("expected line %d of %s, but got line %d", offsets[l.source], l.source, l.offset)
+		}
+
+		if l.source == first.Name && l.offset == 4 && idx < 8 {
+			t.Errorf("expected lines written to %s later to follow the lines already written, but it was observed at %d", first.Name, idx+1)
+		}
+	}
+
+	if offsets[first.Name] != 5 {
+		t.Errorf("expected 5 lines of %s, but got %d", first.Name, offsets[first.Name])
+	} else if offsets[last.Name] != 7 {
+		t.Errorf("expected 7 lines of %s, but got %d", last.Name, offsets[last.Name])
+	}
+}
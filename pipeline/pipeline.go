@@ -0,0 +1,122 @@
+package pipeline
+
+import (
+	"context"
+
+	"github.com/dpb587/bosh-log-tracer/log"
+	"github.com/dpb587/bosh-log-tracer/log/input"
+	"github.com/dpb587/bosh-log-tracer/observer"
+)
+
+// Run parses every line of an input and hands it to the observer. If the parser
+// is a log.EntryMatcher, continuation lines are first folded into the entry
+// they belong to. The observer is committed even if processing stopped early so
+// a partial result is still available.
+func Run(in input.Input, lineParser log.LineParser, obs observer.Observer) error {
+	err := obs.Begin()
+	if err != nil {
+		return err
+	}
+
+	parseConcurrently, parseOrdered, drain := splitParser(lineParser)
+
+	scanErr := scan(in, lineParser, nil, func(l log.Line) error {
+		l, err := parseConcurrently(l)
+		if err != nil {
+			return err
+		}
+
+		lines, err := parseOrdered(l)
+		if err != nil {
+			return err
+		}
+
+		return observe(obs, lines)
+	})
+
+	if scanErr == nil {
+		var lines []log.Line
+
+		lines, scanErr = drain()
+		if scanErr == nil {
+			scanErr = observe(obs, lines)
+		}
+	}
+
+	err = obs.Commit()
+	if scanErr != nil {
+		return scanErr
+	}
+
+	return err
+}
+
+// observe hands parsed lines to the observer in order.
+func observe(obs observer.Observer, lines []log.Line) error {
+	for _, l := range lines {
+		err := obs.Handle(l)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// splitParser returns how lines are parsed concurrently, how their results are
+// then completed in the order of the input, and how lines still held by the
+// parser are completed once the input ended.
+func splitParser(lineParser log.LineParser) (func(log.Line) (log.Line, error), func(log.Line) ([]log.Line, error), func() ([]log.Line, error)) {
+	switch p := lineParser.(type) {
+	case log.BufferedParser:
+		return p.ParseConcurrently, p.ParseBuffered, p.Drain
+	case log.OrderedParser:
+		return p.ParseConcurrently, func(l log.Line) ([]log.Line, error) {
+			l, err := p.ParseOrdered(l)
+			if err != nil {
+				return nil, err
+			}
+
+			return []log.Line{l}, nil
+		}, noDrain
+	}
+
+	return lineParser.Parse, func(l log.Line) ([]log.Line, error) {
+		return []log.Line{l}, nil
+	}, noDrain
+}
+
+func noDrain() ([]log.Line, error) {
+	return nil, nil
+}
+
+// scan calls handler for every entry of an input, assembling entries of
+// several lines when the parser supports it. If follow is set, the input is
+// read past its end until follow is done.
+func scan(in input.Input, lineParser log.LineParser, follow context.Context, handler func(log.Line) error) error {
+	add, idle := handler, func() error { return nil }
+
+	if matcher, ok := lineParser.(log.EntryMatcher); ok {
+		assembler := log.NewAssembler(matcher, handler)
+
+		// while following, an entry is complete once nothing more was written
+		// rather than only once the next entry starts
+		add, idle = assembler.Add, assembler.Flush
+	}
+
+	if follow != nil {
+		var err error
+
+		in, err = in.Follow(follow, followPollInterval, idle)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := in.Scan(add)
+	if err != nil {
+		return err
+	}
+
+	return idle()
+}
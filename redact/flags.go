@@ -0,0 +1,50 @@
+package redact
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// Flags are the redaction options shared by commands.
+type Flags struct {
+	patterns   patternsFlag
+	noDefaults bool
+}
+
+type patternsFlag []string
+
+func (f *patternsFlag) String() string {
+	return strings.Join(*f, ", ")
+}
+
+func (f *patternsFlag) Set(v string) error {
+	*f = append(*f, v)
+
+	return nil
+}
+
+func (f *Flags) Register(fs *flag.FlagSet) {
+	fs.Var(&f.patterns, "redact", "additional regular expression to redact; when it has groups, only the groups are redacted (may be repeated)")
+	fs.BoolVar(&f.noDefaults, "no-default-redactions", false, "do not redact the built-in secrets of agent settings, specs, credentials and SQL values")
+}
+
+// Redactor builds a redactor from the parsed flags.
+func (f *Flags) Redactor() (*Redactor, error) {
+	var rules []Rule
+
+	if !f.noDefaults {
+		rules = append(rules, DefaultRules()...)
+	}
+
+	for _, pattern := range f.patterns {
+		rule, err := NewRegexpRule(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("parsing redaction %s: %s", pattern, err)
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return NewRedactor(rules...), nil
+}
@@ -0,0 +1,179 @@
+package redact
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/dpb587/bosh-log-tracer/trace"
+)
+
+// Placeholder replaces redacted values.
+const Placeholder = "<redacted>"
+
+// Rule removes sensitive data from a string.
+type Rule interface {
+	Redact(string) string
+}
+
+// Redactor applies rules to everything which may leave the process, such as
+// span tags and the log lines referenced by spans.
+type Redactor struct {
+	rules []Rule
+}
+
+func NewRedactor(rules ...Rule) *Redactor {
+	return &Redactor{
+		rules: rules,
+	}
+}
+
+// String returns s with every rule applied. A nil redactor leaves s as-is.
+func (r *Redactor) String(s string) string {
+	if r == nil {
+		return s
+	}
+
+	for _, rule := range r.rules {
+		s = rule.Redact(s)
+	}
+
+	return s
+}
+
+// Trace redacts the values of tags, event fields and statuses of every span,
+// including strings nested in maps, slices and structs.
+func (r *Redactor) Trace(tr *trace.Trace) {
+	if r == nil {
+		return
+	}
+
+	for _, sp := range tr.Spans {
+		sp.OperationName = r.String(sp.OperationName)
+		sp.Status.Message = r.String(sp.Status.Message)
+
+		r.tags(sp.Tags)
+
+		for _, event := range sp.Events {
+			r.tags(event.Fields)
+		}
+	}
+}
+
+func (r *Redactor) tags(tags []trace.Tag) {
+	for idx, tag := range tags {
+		if r.sensitiveKey(tag.Key[strings.LastIndex(tag.Key, ".")+1:]) {
+			// flattened payloads (e.g. updater.change.env.new.password)
+			tags[idx].Value = Placeholder
+
+			continue
+		}
+
+		switch v := tag.Value.(type) {
+		case string:
+			tags[idx].Value = r.String(v)
+		case bool, int, int32, int64, uint32, float32, float64:
+			// nothing to redact
+		default:
+			tags[idx].Value = r.Value(v)
+		}
+	}
+}
+
+// sensitiveKey is whether values of the key are replaced by a KeyRule.
+func (r *Redactor) sensitiveKey(key string) bool {
+	for _, rule := range r.rules {
+		if keyRule, ok := rule.(KeyRule); ok && keyRule.Keys.MatchString(key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Value returns a copy of v with every string it contains redacted; only
+// exported struct fields are considered. Values of map keys which are
+// sensitive to a KeyRule are replaced entirely.
+func (r *Redactor) Value(v interface{}) interface{} {
+	if r == nil || v == nil {
+		return v
+	}
+
+	return r.value(reflect.ValueOf(v)).Interface()
+}
+
+func (r *Redactor) value(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.String:
+		res := reflect.New(v.Type()).Elem()
+		res.SetString(r.String(v.String()))
+
+		return res
+	case reflect.Struct:
+		res := reflect.New(v.Type()).Elem()
+		res.Set(v)
+
+		for i := 0; i < v.NumField(); i++ {
+			if res.Field(i).CanSet() {
+				res.Field(i).Set(r.value(v.Field(i)))
+			}
+		}
+
+		return res
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+
+		res := reflect.MakeMapWithSize(v.Type(), v.Len())
+
+		for _, key := range v.MapKeys() {
+			if key.Kind() == reflect.String && r.sensitiveKey(key.String()) {
+				res.SetMapIndex(key, placeholderValue(v.Type().Elem()))
+
+				continue
+			}
+
+			res.SetMapIndex(key, r.value(v.MapIndex(key)))
+		}
+
+		return res
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+
+		res := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+
+		for i := 0; i < v.Len(); i++ {
+			res.Index(i).Set(r.value(v.Index(i)))
+		}
+
+		return res
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+
+		res := reflect.New(v.Type()).Elem()
+		res.Set(r.value(v.Elem()))
+
+		return res
+	}
+
+	return v
+}
+
+// placeholderValue is Placeholder as a value of the type, or its zero value
+// when the type cannot hold a string.
+func placeholderValue(t reflect.Type) reflect.Value {
+	placeholder := reflect.ValueOf(Placeholder)
+
+	switch {
+	case placeholder.Type().AssignableTo(t):
+		return placeholder
+	case t.Kind() == reflect.String:
+		return placeholder.Convert(t)
+	}
+
+	return reflect.Zero(t)
+}
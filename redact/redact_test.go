@@ -0,0 +1,126 @@
+package redact
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dpb587/bosh-log-tracer/trace"
+)
+
+type payload struct {
+	Name     string
+	Settings map[string]interface{}
+	hidden   string
+}
+
+func TestRedactorTrace(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected interface{}
+	}{
+		{
+			name:     "string",
+			value:    `{"password":"x"}`,
+			expected: `{"password":"<redacted>"}`,
+		},
+		{
+			name:     "strings",
+			value:    []string{"nats://a:b@host", "plain"},
+			expected: []string{"nats://<redacted>@host", "plain"},
+		},
+		{
+			name:     "scalar",
+			value:    int64(42),
+			expected: int64(42),
+		},
+		{
+			name: "map",
+			value: map[string]interface{}{
+				"password": "x",
+				"url":      "https://a:b@host",
+				"env":      map[string]interface{}{"bosh": "y"},
+				"name":     "z",
+			},
+			expected: map[string]interface{}{
+				"password": Placeholder,
+				"url":      "https://<redacted>@host",
+				"env":      Placeholder,
+				"name":     "z",
+			},
+		},
+		{
+			name:     "typed map",
+			value:    map[string]int{"token": 1, "count": 2},
+			expected: map[string]int{"token": 0, "count": 2},
+		},
+		{
+			name: "struct",
+			value: payload{
+				Name:     `{"secret":"x"}`,
+				Settings: map[string]interface{}{"private_key": "k", "list": []interface{}{`{"token":"t"}`}},
+				hidden:   "h",
+			},
+			expected: payload{
+				Name:     `{"secret":"<redacted>"}`,
+				Settings: map[string]interface{}{"private_key": Placeholder, "list": []interface{}{`{"token":"<redacted>"}`}},
+				hidden:   "h",
+			},
+		},
+		{
+			name:     "flattened key",
+			value:    "s3cr3t",
+			expected: Placeholder,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := "test.value"
+			if tt.name == "flattened key" {
+				key = "updater.change.env.new.password"
+			}
+
+			tr := trace.NewTrace(nil)
+			sp := tr.StartSpan("worker", "task", nil, time.Time{}, trace.Tag{Key: key, Value: tt.value})
+			sp.AddEvent(time.Time{}, "event", trace.Tag{Key: key, Value: tt.value})
+
+			NewRedactor(DefaultRules()...).Trace(tr)
+
+			if actual := sp.Tags[0].Value; !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("tag: expected %#v, but got %#v", tt.expected, actual)
+			}
+
+			if actual := sp.Events[0].Fields[0].Value; !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("event field: expected %#v, but got %#v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestRedactorTraceStatus(t *testing.T) {
+	tr := trace.NewTrace(nil)
+	sp := tr.StartSpan("worker", "connect nats://a:b@host", nil, time.Time{})
+	sp.SetError(time.Time{}, "error", `UPDATE "vms" SET "agent_id" = 'abc'`)
+
+	NewRedactor(DefaultRules()...).Trace(tr)
+
+	if expected := "connect nats://<redacted>@host"; sp.OperationName != expected {
+		t.Errorf("expected operation %q, but got %q", expected, sp.OperationName)
+	} else if expected := `UPDATE "vms" SET "agent_id" = '<redacted>'`; sp.Status.Message != expected {
+		t.Errorf("expected status %q, but got %q", expected, sp.Status.Message)
+	}
+}
+
+func TestRedactorNil(t *testing.T) {
+	var r *Redactor
+
+	value := map[string]interface{}{"password": "x"}
+
+	if actual := r.String(`{"password":"x"}`); actual != `{"password":"x"}` {
+		t.Errorf("expected string to be unchanged, but got %s", actual)
+	} else if actual := r.Value(value); !reflect.DeepEqual(actual, value) {
+		t.Errorf("expected value to be unchanged, but got %#v", actual)
+	}
+}
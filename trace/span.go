@@ -0,0 +1,139 @@
+package trace
+
+import (
+	"fmt"
+	"time"
+)
+
+type Span struct {
+	TraceID  TraceID
+	ID       SpanID
+	ParentID SpanID
+
+	// Key identifies the span by its ancestry, service, operation and start
+	// rather than a random ID; it is stable between runs over the same log.
+	Key string
+
+	Service       string
+	OperationName string
+	StartTime     time.Time
+	FinishTime    time.Time
+	Finished      bool
+
+	Tags   []Tag
+	Events []Event
+	Status Status
+}
+
+type Tag struct {
+	Key   string
+	Value interface{}
+}
+
+// ScalarValue returns the value as one of string, bool, int64 or float64;
+// anything else is formatted as a string.
+func (t Tag) ScalarValue() interface{} {
+	switch v := t.Value.(type) {
+	case string, bool, int64, float64:
+		return v
+	case int:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case uint32:
+		return int64(v)
+	case float32:
+		return float64(v)
+	}
+
+	return fmt.Sprintf("%v", t.Value)
+}
+
+// Event is something which happened during a span, such as a log line which
+// started or finished it.
+type Event struct {
+	Time   time.Time
+	Name   string
+	Fields []Tag
+}
+
+type StatusCode int
+
+const (
+	StatusUnset StatusCode = iota
+	StatusError
+)
+
+type Status struct {
+	Code    StatusCode
+	Message string
+}
+
+func (s *Span) SetOperationName(operationName string) {
+	s.OperationName = operationName
+}
+
+// SetTag adds a tag, replacing the value of an existing tag with the same key.
+func (s *Span) SetTag(key string, value interface{}) {
+	for idx, tag := range s.Tags {
+		if tag.Key == key {
+			s.Tags[idx].Value = value
+
+			return
+		}
+	}
+
+	s.Tags = append(s.Tags, Tag{Key: key, Value: value})
+}
+
+func (s *Span) GetTag(key string) (interface{}, bool) {
+	for _, tag := range s.Tags {
+		if tag.Key == key {
+			return tag.Value, true
+		}
+	}
+
+	return nil, false
+}
+
+func (s *Span) AddEvent(t time.Time, name string, fields ...Tag) {
+	s.Events = append(s.Events, Event{
+		Time:   t,
+		Name:   name,
+		Fields: fields,
+	})
+}
+
+// SetError marks the span as failed; the first error message is kept as the
+// status while each one is recorded as an event along with any extra fields
+// (e.g. a stack).
+func (s *Span) SetError(t time.Time, kind, message string, fields ...Tag) {
+	if s.Status.Code != StatusError {
+		s.Status = Status{
+			Code:    StatusError,
+			Message: message,
+		}
+	}
+
+	s.AddEvent(
+		t,
+		"error",
+		append([]Tag{
+			{Key: "error.kind", Value: kind},
+			{Key: "message", Value: message},
+		}, fields...)...,
+	)
+}
+
+func (s *Span) IsError() bool {
+	return s.Status.Code == StatusError
+}
+
+func (s *Span) Finish(t time.Time) {
+	s.FinishTime = t
+	s.Finished = true
+}
+
+func (s *Span) Duration() time.Duration {
+	return s.FinishTime.Sub(s.StartTime)
+}
@@ -0,0 +1,198 @@
+package trace
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// TraceID follows the jaeger convention of the root span sharing the (low) ID
+// of its trace. High is zero for 64-bit IDs.
+type TraceID struct {
+	High uint64
+	Low  uint64
+}
+
+func (id TraceID) String() string {
+	if id.High == 0 {
+		return fmt.Sprintf("%016x", id.Low)
+	}
+
+	return fmt.Sprintf("%016x%016x", id.High, id.Low)
+}
+
+func (id TraceID) IsZero() bool {
+	return id.High == 0 && id.Low == 0
+}
+
+type SpanID uint64
+
+func (id SpanID) String() string {
+	return fmt.Sprintf("%016x", uint64(id))
+}
+
+// IDGenerator creates the identifiers of traces and spans; they must never be
+// zero.
+type IDGenerator interface {
+	TraceID() TraceID
+	SpanID() SpanID
+}
+
+type randomIDGenerator struct {
+	random *rand.Rand
+	mu     sync.Mutex
+}
+
+func NewRandomIDGenerator() IDGenerator {
+	return &randomIDGenerator{
+		random: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+func (g *randomIDGenerator) TraceID() TraceID {
+	return TraceID{Low: uint64(g.SpanID())}
+}
+
+func (g *randomIDGenerator) SpanID() SpanID {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for {
+		if v := g.random.Uint64(); v != 0 {
+			return SpanID(v)
+		}
+	}
+}
+
+// Trace is the in-memory timeline of a task which exporters convert to their
+// own formats.
+type Trace struct {
+	ID    TraceID
+	Spans []*Span
+
+	ids  IDGenerator
+	keys map[string]int
+}
+
+func NewTrace(ids IDGenerator) *Trace {
+	if ids == nil {
+		ids = NewRandomIDGenerator()
+	}
+
+	return &Trace{
+		ids:  ids,
+		keys: map[string]int{},
+	}
+}
+
+// StartSpan adds a new span to the trace. The first span without a parent
+// decides the ID of the trace.
+func (t *Trace) StartSpan(service, operationName string, parent *Span, startTime time.Time, tags ...Tag) *Span {
+	sp := &Span{
+		Service:       service,
+		OperationName: operationName,
+		StartTime:     startTime,
+		Tags:          append([]Tag(nil), tags...),
+	}
+
+	sp.Key = fmt.Sprintf("%s:%s@%d", service, operationName, startTime.UnixNano())
+	if parent != nil {
+		sp.Key = fmt.Sprintf("%s/%s", parent.Key, sp.Key)
+	}
+
+	t.keys[sp.Key]++
+	if n := t.keys[sp.Key]; n > 1 {
+		sp.Key = fmt.Sprintf("%s#%d", sp.Key, n)
+	}
+
+	if parent != nil {
+		sp.TraceID = parent.TraceID
+		sp.ID = t.ids.SpanID()
+		sp.ParentID = parent.ID
+	} else if t.ID.IsZero() {
+		t.ID = t.ids.TraceID()
+		sp.TraceID = t.ID
+		sp.ID = SpanID(t.ID.Low)
+	} else {
+		sp.TraceID = t.ID
+		sp.ID = t.ids.SpanID()
+	}
+
+	t.Spans = append(t.Spans, sp)
+
+	return sp
+}
+
+// DeriveIDs replaces the random IDs of the trace and its spans with ones
+// derived from the seed and the key of each span. Tracing the same log with the
+// same seed always results in the same IDs.
+func (t *Trace) DeriveIDs(seed string) {
+	if len(t.Spans) == 0 {
+		return
+	}
+
+	rootID := SpanID(t.ID.Low)
+	traceSum := sha256.Sum256([]byte(seed))
+
+	t.ID = TraceID{
+		High: binary.BigEndian.Uint64(traceSum[0:8]),
+		Low:  nonZero(binary.BigEndian.Uint64(traceSum[8:16])),
+	}
+
+	replaced := map[SpanID]SpanID{}
+
+	for _, sp := range t.Spans {
+		id := SpanID(t.ID.Low)
+
+		if sp.ID != rootID || sp.ParentID != 0 {
+			spanSum := sha256.Sum256([]byte(seed + "/" + sp.Key))
+			id = SpanID(nonZero(binary.BigEndian.Uint64(spanSum[0:8])))
+		}
+
+		replaced[sp.ID] = id
+		sp.ID = id
+		sp.TraceID = t.ID
+	}
+
+	for _, sp := range t.Spans {
+		if sp.ParentID != 0 {
+			sp.ParentID = replaced[sp.ParentID]
+		}
+	}
+}
+
+func nonZero(v uint64) uint64 {
+	if v == 0 {
+		return 1
+	}
+
+	return v
+}
+
+// RootSpan returns the first span without a parent, typically the task.
+func (t *Trace) RootSpan() (*Span, bool) {
+	for _, sp := range t.Spans {
+		if sp.ParentID == 0 {
+			return sp, true
+		}
+	}
+
+	return nil, false
+}
+
+// FinishedSpans returns the spans which have been finished, in the order they
+// were started.
+func (t *Trace) FinishedSpans() []*Span {
+	var res []*Span
+
+	for _, sp := range t.Spans {
+		if sp.Finished {
+			res = append(res, sp)
+		}
+	}
+
+	return res
+}